@@ -0,0 +1,209 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+const version = "ccwc 1.0"
+
+// longAliases are the GNU wc long names of the single letter flags
+var longAliases = map[string]string{
+	"bytes":           "c",
+	"chars":           "m",
+	"lines":           "l",
+	"words":           "w",
+	"max-line-length": "L",
+	"recursive":       "r",
+	"follow":          "f",
+	"jobs":            "j",
+}
+
+// normalizeArgs rewrites a GNU style command line into one flag.FlagSet can
+// parse. Short flags can be bundled as in -lw, and a short flag that takes a
+// value takes the rest of its bundle as in -j4. Long options are written
+// with two dashes, take their value after = or as the next argument, and can
+// be abbreviated to any unique prefix. Operands can come before options, and
+// everything after -- is an operand.
+//
+// For compatibility with the Go flag syntax, a single dash followed by the
+// full name of a long option is that option.
+func normalizeArgs(flagSet *flag.FlagSet, args []string) ([]string, error) {
+	var flags, operands []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--":
+			operands = append(operands, args[i+1:]...)
+			i = len(args)
+		case arg == "-" || !strings.HasPrefix(arg, "-"):
+			operands = append(operands, arg)
+		case strings.HasPrefix(arg, "--"):
+			name, value, inline := strings.Cut(arg[2:], "=")
+			f, err := findLong(flagSet, name)
+			if err != nil {
+				return nil, err
+			}
+			if isBoolFlag(f) {
+				flags = append(flags, "-"+f.Name+suffix(value, inline))
+				continue
+			}
+			if !inline {
+				if i+1 == len(args) {
+					return nil, fmt.Errorf("option '--%s' requires an argument", f.Name)
+				}
+				i++
+				value = args[i]
+			}
+			flags = append(flags, "-"+f.Name+"="+value)
+		default:
+			if name, _, _ := strings.Cut(arg[1:], "="); len(name) > 1 && flagSet.Lookup(name) != nil {
+				flags = append(flags, arg)
+				continue
+			}
+			bundle, err := expandBundle(flagSet, arg[1:], args[i+1:])
+			if err != nil {
+				return nil, err
+			}
+			for _, f := range bundle {
+				if f.consumed {
+					i++
+				}
+				flags = append(flags, f.arg)
+			}
+		}
+	}
+	return append(append(flags, "--"), operands...), nil
+}
+
+func suffix(value string, inline bool) string {
+	if !inline {
+		return ""
+	}
+	return "=" + value
+}
+
+func isBoolFlag(f *flag.Flag) bool {
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+// findLong returns the flag of a long option, which may be abbreviated
+func findLong(flagSet *flag.FlagSet, name string) (*flag.Flag, error) {
+	if alias, ok := longAliases[name]; ok {
+		return flagSet.Lookup(alias), nil
+	}
+	if f := flagSet.Lookup(name); f != nil && len(name) > 1 {
+		return f, nil
+	}
+
+	var matches []string
+	for _, long := range longNames(flagSet) {
+		if strings.HasPrefix(long, name) {
+			matches = append(matches, long)
+		}
+	}
+	switch {
+	case name == "" || len(matches) == 0:
+		return nil, fmt.Errorf("unrecognized option '--%s'", name)
+	case len(matches) > 1:
+		return nil, fmt.Errorf("option '--%s' is ambiguous; possibilities: '--%s'", name, strings.Join(matches, "' '--"))
+	}
+	return findLong(flagSet, matches[0])
+}
+
+// longNames returns every long option, sorted
+func longNames(flagSet *flag.FlagSet) []string {
+	var names []string
+	for alias := range longAliases {
+		names = append(names, alias)
+	}
+	flagSet.VisitAll(func(f *flag.Flag) {
+		if len(f.Name) > 1 {
+			names = append(names, f.Name)
+		}
+	})
+	sort.Strings(names)
+	return names
+}
+
+type bundledFlag struct {
+	arg      string
+	consumed bool // the value is the next argument
+}
+
+// expandBundle splits the letters of a bundle of short flags. next are the
+// arguments that follow the bundle.
+func expandBundle(flagSet *flag.FlagSet, letters string, next []string) ([]bundledFlag, error) {
+	var bundle []bundledFlag
+	for i, letter := range letters {
+		f := flagSet.Lookup(string(letter))
+		if f == nil {
+			return nil, fmt.Errorf("invalid option -- '%c'", letter)
+		}
+		if isBoolFlag(f) {
+			bundle = append(bundle, bundledFlag{arg: "-" + f.Name})
+			continue
+		}
+
+		if value := letters[i+len(string(letter)):]; value != "" {
+			return append(bundle, bundledFlag{arg: "-" + f.Name + "=" + value}), nil
+		}
+		if len(next) == 0 {
+			return nil, fmt.Errorf("option requires an argument -- '%c'", letter)
+		}
+		return append(bundle, bundledFlag{arg: "-" + f.Name + "=" + next[0], consumed: true}), nil
+	}
+	return bundle, nil
+}
+
+// printUsage prints the help of every flag, the short ones with their long
+// alias, in the layout of GNU --help
+func printUsage(w io.Writer, flagSet *flag.FlagSet) {
+	fmt.Fprint(w, `Usage: ccwc [OPTION]... [FILE]...
+  or:  ccwc [OPTION]... --files0-from=F
+Print newline, word, and byte counts for each FILE, and a total line if
+more than one FILE is specified. A word is a non-zero-length sequence of
+characters delimited by white space.
+
+With no FILE, read standard input.
+
+The options below may be used to select which counts are printed, always in
+the following order: newline, word, character, byte, maximum line length.
+`)
+
+	aliases := map[string]string{}
+	for alias, short := range longAliases {
+		aliases[short] = alias
+	}
+	flagSet.VisitAll(func(f *flag.Flag) {
+		valueName, usage := flag.UnquoteUsage(f)
+		if f.DefValue != "" && f.DefValue != "false" && f.DefValue != "0" {
+			usage += fmt.Sprintf(" (default %s)", f.DefValue)
+		}
+		var option string
+		switch alias, ok := aliases[f.Name]; {
+		case ok && valueName != "":
+			option = fmt.Sprintf("  -%s, --%s=%s", f.Name, alias, valueName)
+		case ok:
+			option = fmt.Sprintf("  -%s, --%s", f.Name, alias)
+		case len(f.Name) == 1 && valueName != "":
+			option = fmt.Sprintf("  -%s %s", f.Name, valueName)
+		case len(f.Name) == 1:
+			option = fmt.Sprintf("  -%s", f.Name)
+		case valueName != "":
+			option = fmt.Sprintf("      --%s=%s", f.Name, valueName)
+		default:
+			option = fmt.Sprintf("      --%s", f.Name)
+		}
+
+		if len(option) < 30 {
+			fmt.Fprintf(w, "%-30s%s\n", option, usage)
+		} else {
+			fmt.Fprintf(w, "%s\n%30s%s\n", option, "", usage)
+		}
+	})
+}
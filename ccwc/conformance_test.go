@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"strings"
+	"testing"
+)
+
+// conformanceCase is a command of testdata/gnu/wc.txt and the output GNU wc
+// printed for it
+type conformanceCase struct {
+	args   []string
+	stdin  string // the file redirected to stdin, if any
+	output string
+}
+
+// readConformanceCases reads the commands of a transcript. A command is a line
+// starting with "$ wc", followed by its output up to the next blank line.
+func readConformanceCases(t *testing.T, path string) []conformanceCase {
+	t.Helper()
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	var cases []conformanceCase
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "$ wc"):
+			command, stdin, _ := strings.Cut(strings.TrimPrefix(line, "$ wc"), "<")
+			cases = append(cases, conformanceCase{
+				args:  strings.Fields(command),
+				stdin: strings.TrimSpace(stdin),
+			})
+		case line == "", strings.HasPrefix(line, "#"):
+		case len(cases) > 0:
+			cases[len(cases)-1].output += line + "\n"
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return cases
+}
+
+// TestGNUConformance runs the commands recorded from GNU wc, whose operands
+// are relative to testdata/gnu
+func TestGNUConformance(t *testing.T) {
+	cases := readConformanceCases(t, "testdata/gnu/wc.txt")
+	if len(cases) == 0 {
+		t.Fatal("no commands in testdata/gnu/wc.txt")
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir("testdata/gnu"); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	for _, tt := range cases {
+		name := strings.Join(tt.args, " ")
+		if tt.stdin != "" {
+			name += " < " + tt.stdin
+		}
+		t.Run(name, func(t *testing.T) {
+			stdin := os.Stdin
+			if tt.stdin != "" {
+				file, err := os.Open(tt.stdin)
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer file.Close()
+				stdin = file
+			}
+
+			var stdout, stderr strings.Builder
+			if code := run(tt.args, stdin, &stdout, &stderr); code != exitOK {
+				t.Errorf("exit code = %d, want %d (stderr %q)", code, exitOK, stderr.String())
+			}
+			if stdout.String() != tt.output {
+				t.Errorf("stdout = %q, want %q", stdout.String(), tt.output)
+			}
+		})
+	}
+}
@@ -0,0 +1,280 @@
+package main
+
+import (
+	"ccwc/wc"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+)
+
+// Status of a file in a comparison
+const (
+	statusAdded     = "added"
+	statusRemoved   = "removed"
+	statusChanged   = "changed"
+	statusUnchanged = "unchanged"
+	statusError     = "error"
+)
+
+// diffPair is a file to compare. A path is empty when the file is missing
+// on that side. err is set when a side could not be walked.
+type diffPair struct {
+	name             string
+	oldPath, newPath string
+	err              error
+}
+
+// diffEntry holds the counts of a file on both sides of a comparison. A side
+// is nil when the file is missing there.
+type diffEntry struct {
+	name     string
+	old, new *wc.Result
+	err      error
+}
+
+func (e diffEntry) status(metrics []metric) string {
+	switch {
+	case e.err != nil:
+		return statusError
+	case e.old == nil:
+		return statusAdded
+	case e.new == nil:
+		return statusRemoved
+	}
+	for _, m := range metrics {
+		if m.value(*e.old) != m.value(*e.new) {
+			return statusChanged
+		}
+	}
+	return statusUnchanged
+}
+
+// side returns the value of m on one side, 0 when the file is missing
+func side(counts *wc.Result, m metric) int {
+	if counts == nil {
+		return 0
+	}
+	return m.value(*counts)
+}
+
+// percent returns the change from old to new in percent, and false when old
+// is 0 so no percentage makes sense
+func percent(old, new int) (float64, bool) {
+	if old == 0 {
+		return 0, false
+	}
+	return float64(new-old) * 100 / float64(old), true
+}
+
+// diffPairs matches the files to compare. Two files are compared with each
+// other, and two directories file by file, by their path relative to the
+// directory. The files of a directory are found like with -r.
+func diffPairs(oldRoot, newRoot string, walker Walker) ([]diffPair, error) {
+	oldInfo, err := os.Stat(oldRoot)
+	if err != nil {
+		return nil, err
+	}
+	newInfo, err := os.Stat(newRoot)
+	if err != nil {
+		return nil, err
+	}
+	if oldInfo.IsDir() != newInfo.IsDir() {
+		return nil, fmt.Errorf("cannot compare a file with a directory")
+	}
+	if !oldInfo.IsDir() {
+		return []diffPair{{name: oldRoot + " -> " + newRoot, oldPath: oldRoot, newPath: newRoot}}, nil
+	}
+
+	pairs := map[string]*diffPair{}
+	collect := func(root string, setPath func(pair *diffPair, path string)) {
+		walker.walk(root, func(path string, err error) {
+			rel, relErr := filepath.Rel(root, path)
+			if relErr != nil {
+				rel = path
+			}
+			pair, ok := pairs[rel]
+			if !ok {
+				pair = &diffPair{name: rel}
+				pairs[rel] = pair
+			}
+			setPath(pair, path)
+			if err != nil && pair.err == nil {
+				pair.err = fmt.Errorf("%s: %w", path, reason(err))
+			}
+		})
+	}
+	collect(oldRoot, func(pair *diffPair, path string) { pair.oldPath = path })
+	collect(newRoot, func(pair *diffPair, path string) { pair.newPath = path })
+
+	sorted := make([]diffPair, 0, len(pairs))
+	for _, pair := range pairs {
+		sorted = append(sorted, *pair)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].name < sorted[j].name })
+	return sorted, nil
+}
+
+// countPairs counts both sides of every pair with countFiles
+func countPairs(pairs []diffPair, jobs int, options wc.Options) []diffEntry {
+	produce := func(fn func(path string, err error)) error {
+		for _, pair := range pairs {
+			if pair.err != nil {
+				continue
+			}
+			for _, path := range []string{pair.oldPath, pair.newPath} {
+				if path != "" {
+					fn(path, nil)
+				}
+			}
+		}
+		return nil
+	}
+	results := map[string]fileResult{}
+	countFiles(produce, jobs, options, nil, func(result fileResult) {
+		results[result.path] = result
+	})
+
+	entries := make([]diffEntry, 0, len(pairs))
+	for _, pair := range pairs {
+		entry := diffEntry{name: pair.name, err: pair.err}
+		if entry.err != nil {
+			entries = append(entries, entry)
+			continue
+		}
+		for _, s := range []struct {
+			path   string
+			counts **wc.Result
+		}{{pair.oldPath, &entry.old}, {pair.newPath, &entry.new}} {
+			if s.path == "" {
+				continue
+			}
+			result := results[s.path]
+			if result.err != nil {
+				entry.err = fmt.Errorf("%s: %w", s.path, reason(result.err))
+				continue
+			}
+			*s.counts = &result.counts
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// diffTotal sums both sides of every entry that could be counted
+func diffTotal(entries []diffEntry) diffEntry {
+	var old, new wc.Result
+	for _, e := range entries {
+		if e.err != nil {
+			continue
+		}
+		if e.old != nil {
+			old.Add(*e.old)
+		}
+		if e.new != nil {
+			new.Add(*e.new)
+		}
+	}
+	return diffEntry{name: "total", old: &old, new: &new}
+}
+
+// diffWidth returns the width of the columns of the text output, which fit
+// both sides of the total
+func diffWidth(total diffEntry, metrics []metric) int {
+	width := 1
+	for _, m := range metrics {
+		width = max(width, len(strconv.Itoa(side(total.old, m))), len(strconv.Itoa(side(total.new, m))))
+	}
+	return width
+}
+
+// printDiff prints a heading with the status of an entry, followed by a line
+// per metric with both sides, the delta and the change in percent
+func printDiff(w io.Writer, e diffEntry, status string, metrics []metric, width int) {
+	fmt.Fprintf(w, "%s: %s\n", e.name, status)
+	if e.err != nil {
+		return
+	}
+	nameWidth := 0
+	for _, m := range metrics {
+		nameWidth = max(nameWidth, len(m.name))
+	}
+	for _, m := range metrics {
+		old, new := side(e.old, m), side(e.new, m)
+		change := "-"
+		if p, ok := percent(old, new); ok {
+			change = fmt.Sprintf("%+.1f%%", p)
+		}
+		fmt.Fprintf(w, "  %-*s %*d %*d %+*d %8s\n", nameWidth, m.name, width, old, width, new, width+1, new-old, change)
+	}
+}
+
+// diffRecord returns both sides of an entry with their deltas. A missing
+// side is null, and so is the percentage of a metric that was 0.
+func diffRecord(e diffEntry, metrics []metric) record {
+	values := func(counts *wc.Result) any {
+		if counts == nil || e.err != nil {
+			return nil
+		}
+		r := record{}
+		for _, m := range metrics {
+			r = append(r, field{name: m.name, value: m.field(*counts)})
+		}
+		return r
+	}
+
+	var delta, change any
+	var message any
+	if e.err == nil {
+		deltas, changes := record{}, record{}
+		for _, m := range metrics {
+			old, new := side(e.old, m), side(e.new, m)
+			deltas = append(deltas, field{name: m.name, value: new - old})
+			var value any
+			if p, ok := percent(old, new); ok {
+				value = p
+			}
+			changes = append(changes, field{name: m.name, value: value})
+		}
+		delta, change = deltas, changes
+	} else {
+		message = e.err.Error()
+	}
+
+	return record{
+		{name: "path", value: e.name},
+		{name: "status", value: e.status(metrics)},
+		{name: "old", value: values(e.old)},
+		{name: "new", value: values(e.new)},
+		{name: "delta", value: delta},
+		{name: "percent", value: change},
+		{name: "error", value: message},
+	}
+}
+
+// diffRows returns a row per metric of an entry, without its type. The cells
+// of a missing side are empty.
+func diffRows(e diffEntry, metrics []metric) [][]string {
+	status := e.status(metrics)
+	if e.err != nil {
+		return [][]string{{e.name, status, "", "", "", "", "", e.err.Error()}}
+	}
+	cell := func(counts *wc.Result, m metric) string {
+		if counts == nil {
+			return ""
+		}
+		return m.text(*counts)
+	}
+	rows := make([][]string, 0, len(metrics))
+	for _, m := range metrics {
+		old, new := side(e.old, m), side(e.new, m)
+		change := ""
+		if p, ok := percent(old, new); ok {
+			change = strconv.FormatFloat(p, 'f', 2, 64)
+		}
+		rows = append(rows, []string{e.name, status, m.name, cell(e.old, m), cell(e.new, m), strconv.Itoa(new - old), change, ""})
+	}
+	return rows
+}
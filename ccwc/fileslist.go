@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Names streamed from a list are not known up front, so their sizes cannot be
+// used to compute the column width
+const listWidth = 7
+
+// maxListSize is the size up to which a regular list is read up front, as
+// GNU wc does, so the sizes of its files set the column width
+const maxListSize = 10 << 20
+
+// listEntry is a name read from a list, with errEmptyName for an empty one
+type listEntry struct {
+	name string
+	err  error
+}
+
+// readListUpFront reads the names of the list at path when it is a regular
+// file of at most maxListSize. It reports false for stdin, for other files
+// and when the list cannot be read, which are streamed instead.
+func readListUpFront(path string, sep byte) ([]listEntry, bool) {
+	if path == "-" {
+		return nil, false
+	}
+	stat, err := os.Stat(path)
+	if err != nil || !stat.Mode().IsRegular() || stat.Size() > maxListSize {
+		return nil, false
+	}
+
+	var entries []listEntry
+	err = readNamesFrom(path, sep, nil, func(name string, err error) {
+		entries = append(entries, listEntry{name: name, err: err})
+	})
+	if err != nil {
+		return nil, false
+	}
+	return entries, true
+}
+
+// readNamesFrom opens the list at path, or uses stdin when path is "-", and
+// streams its names to fn. An empty name is passed as the path and number of
+// its entry in the list, as in list:3, along with errEmptyName.
+func readNamesFrom(path string, sep byte, stdin io.Reader, fn func(name string, err error)) error {
+	emit := func(name string, n int) {
+		if name == "" {
+			fn(fmt.Sprintf("%s:%d", path, n), errEmptyName)
+			return
+		}
+		fn(name, nil)
+	}
+	if path == "-" {
+		return readNames(stdin, sep, emit)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return readNames(file, sep, emit)
+}
+
+// readNames calls fn with every name in r and its number in the list, from
+// 1, as soon as it is read. A separator at the end of the list does not
+// produce an empty name.
+func readNames(r io.Reader, sep byte, fn func(name string, n int)) error {
+	reader := bufio.NewReader(r)
+	for n := 1; ; n++ {
+		name, err := reader.ReadString(sep)
+		if len(name) > 0 && name[len(name)-1] == sep {
+			fn(name[:len(name)-1], n)
+		} else if len(name) > 0 {
+			fn(name, n)
+		}
+
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFilesFrom(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"a.txt": "hi\n",
+		"b.txt": "one two\n",
+	})
+	a, b := filepath.Join(dir, "a.txt"), filepath.Join(dir, "b.txt")
+	nulList := filepath.Join(dir, "list0")
+	lineList := filepath.Join(dir, "list")
+	emptyList := filepath.Join(dir, "empty0")
+	for path, content := range map[string]string{
+		nulList:   a + "\x00" + b + "\x00",
+		lineList:  a + "\n" + b,
+		emptyList: a + "\x00\x00" + b,
+	} {
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The sizes of the files in a regular list set the width, as with
+	// operands, while a list streamed from stdin gets a wide default
+	both := " 1  1  3 " + a + "\n 1  2  8 " + b + "\n 2  3 11 total\n"
+	streamed := "      1       1       3 " + a + "\n      1       2       8 " + b + "\n      2       3      11 total\n"
+
+	tests := []struct {
+		name   string
+		args   []string
+		stdin  string
+		code   int
+		stdout string
+		stderr string
+	}{
+		{
+			name:   "NUL-separated list",
+			args:   []string{"--files0-from=" + nulList},
+			stdout: both,
+		},
+		{
+			name:   "newline-separated list",
+			args:   []string{"--files-from=" + lineList},
+			stdout: both,
+		},
+		{
+			name:   "list from stdin",
+			args:   []string{"--files0-from=-"},
+			stdin:  a + "\x00" + b,
+			stdout: streamed,
+		},
+		{
+			name:   "- in a list from stdin",
+			args:   []string{"--files-from=-"},
+			stdin:  a + "\n-\n",
+			code:   exitPartial,
+			stdout: "      1       1       3 " + a + "\n      1       1       3 total\n",
+			stderr: "ccwc: -: " + errStdinName.Error() + "\n",
+		},
+		{
+			name:   "empty name",
+			args:   []string{"--files0-from=" + emptyList},
+			code:   exitPartial,
+			stdout: both,
+			stderr: "ccwc: " + emptyList + ":2: invalid zero-length file name\n",
+		},
+		{
+			name:   "both lists",
+			args:   []string{"--files0-from=" + nulList, "--files-from=" + lineList},
+			code:   exitUsage,
+			stderr: "ccwc: --files0-from and --files-from cannot be combined\n",
+		},
+		{
+			name:   "list and operands",
+			args:   []string{"--files0-from=" + nulList, a},
+			code:   exitUsage,
+			stderr: "ccwc: extra operand '" + a + "': file operands cannot be combined with a list of file names\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, stdout, stderr := runCCWC(t, tt.stdin, tt.args...)
+			if code != tt.code {
+				t.Errorf("exit code = %d, want %d", code, tt.code)
+			}
+			if stdout != tt.stdout {
+				t.Errorf("stdout = %q, want %q", stdout, tt.stdout)
+			}
+			if stderr != tt.stderr {
+				t.Errorf("stderr = %q, want %q", stderr, tt.stderr)
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"ccwc/wc"
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"time"
+)
+
+// Follower keeps counting a file as data is appended to it, like tail -f.
+// Only the new data is read at every check. A truncated file is counted again
+// from its start, and a file that was rotated, that is replaced by a new file
+// under the same name, is counted from the start of the new file.
+type Follower struct {
+	path     string
+	options  wc.Options
+	interval time.Duration
+	// always reports the counts at every interval, even when they did not
+	// change
+	always bool
+
+	file    *os.File
+	info    os.FileInfo // of the open file, to notice rotations
+	offset  int64
+	counter *wc.Counter
+}
+
+// follow reports the counts of the file as soon as it is opened and then
+// whenever they change, until ctx is done
+func (f *Follower) follow(ctx context.Context, report func(counts wc.Result)) error {
+	if err := f.open(); err != nil {
+		return err
+	}
+	defer func() { f.file.Close() }()
+
+	ticker := time.NewTicker(f.interval)
+	defer ticker.Stop()
+
+	reported := false
+	for {
+		changed, err := f.poll()
+		if err != nil {
+			return err
+		}
+		if f.always || !reported || changed {
+			report(f.counter.Result())
+			reported = true
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// open opens the file at path and starts counting it from its start
+func (f *Follower) open() error {
+	file, err := os.Open(f.path)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	f.file, f.info, f.offset = file, info, 0
+	f.counter = wc.NewCounter(fileOptions(f.options, f.path))
+	return nil
+}
+
+// poll counts the data appended since the last poll, and starts over when
+// the file was truncated or rotated. It reports whether the counts changed,
+// which is when data was read or the counting started over.
+func (f *Follower) poll() (bool, error) {
+	// Whatever was written to a rotated file before it was replaced still
+	// counts, so it is read first
+	n, err := f.drain()
+	if err != nil {
+		return false, err
+	}
+	changed := n > 0
+
+	info, err := os.Stat(f.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		// Rotated away and not created again yet
+		return changed, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if !os.SameFile(info, f.info) {
+		f.file.Close()
+		if err := f.open(); err != nil {
+			return false, err
+		}
+		_, err := f.drain()
+		return true, err
+	}
+	if info.Size() < f.offset {
+		if _, err := f.file.Seek(0, io.SeekStart); err != nil {
+			return false, err
+		}
+		f.offset = 0
+		f.counter = wc.NewCounter(fileOptions(f.options, f.path))
+		_, err := f.drain()
+		return true, err
+	}
+	return changed, nil
+}
+
+// drain counts the file from the last offset read to its current end, and
+// returns how many bytes it read
+func (f *Follower) drain() (int64, error) {
+	n, err := io.Copy(f.counter, f.file)
+	f.offset += n
+	return n, err
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"ccwc/wc"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFollowerPoll(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "log")
+	write := func(flag int, content string) {
+		t.Helper()
+		file, err := os.OpenFile(path, flag|os.O_WRONLY|os.O_CREATE, 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer file.Close()
+		if _, err := file.WriteString(content); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(os.O_TRUNC, "one\n")
+
+	f := &Follower{path: path, options: wc.Options{Metrics: wc.MetricBytes | wc.MetricLines | wc.MetricWords}}
+	if err := f.open(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { f.file.Close() }()
+
+	steps := []struct {
+		name    string
+		change  func()
+		changed bool
+		lines   int
+		words   int
+		bytes   int
+	}{
+		{"first poll", func() {}, true, 1, 1, 4},
+		{"unchanged", func() {}, false, 1, 1, 4},
+		{"append", func() { write(os.O_APPEND, "two three\n") }, true, 2, 3, 14},
+		{"append a partial line", func() { write(os.O_APPEND, "fo") }, true, 2, 4, 16},
+		{"finish the line", func() { write(os.O_APPEND, "ur\n") }, true, 3, 4, 19},
+		{"truncate", func() { write(os.O_TRUNC, "x\n") }, true, 1, 1, 2},
+		{"rotate", func() {
+			write(os.O_APPEND, "lost\n")
+			if err := os.Rename(path, path+".1"); err != nil {
+				t.Fatal(err)
+			}
+		}, true, 2, 2, 7},
+		{"rotated away", func() {}, false, 2, 2, 7},
+		{"created again", func() { write(os.O_TRUNC, "a b c\n") }, true, 1, 3, 6},
+	}
+	for _, step := range steps {
+		step.change()
+		changed, err := f.poll()
+		if err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		got := f.counter.Result()
+		if changed != step.changed || got.Lines != step.lines || got.Words != step.words || got.Bytes != step.bytes {
+			t.Errorf("%s: changed %t, %d lines, %d words, %d bytes; want %t, %d, %d, %d", step.name,
+				changed, got.Lines, got.Words, got.Bytes, step.changed, step.lines, step.words, step.bytes)
+		}
+	}
+}
@@ -0,0 +1,435 @@
+package main
+
+import (
+	"bytes"
+	"ccwc/wc"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// Formatter writes the result of every input and the final total
+type Formatter interface {
+	file(result fileResult)
+	// frequencies gives the tables of frequent words and chars, which are
+	// written along with the total
+	frequencies(tables []frequencyTable)
+	// total writes the sum of the files, which has no path
+	total(result fileResult, files int)
+	close() error
+}
+
+// frequencyTable lists the most frequent words or chars of every input
+type frequencyTable struct {
+	kind    string // word or char
+	entries []wc.Entry
+}
+
+// records returns a record per entry. The error is how much a count may be
+// overestimated, which is 0 unless the table ran out of capacity.
+func (t frequencyTable) records() []record {
+	records := make([]record, 0, len(t.entries))
+	for _, e := range t.entries {
+		records = append(records, record{
+			{name: t.kind, value: e.Value},
+			{name: "count", value: e.Count},
+			{name: "error", value: e.Error},
+		})
+	}
+	return records
+}
+
+// cells returns the counts of the entries as they are printed. An
+// approximate count is the range the actual count is in.
+func (t frequencyTable) cells() []string {
+	cells := make([]string, 0, len(t.entries))
+	for _, e := range t.entries {
+		cell := strconv.Itoa(e.Count)
+		if e.Error > 0 {
+			cell = fmt.Sprintf("%d-%d", e.Count-e.Error, e.Count)
+		}
+		cells = append(cells, cell)
+	}
+	return cells
+}
+
+// When the text output prints the total line, as GNU wc --total
+const (
+	totalAuto   = "auto" // with more than one input
+	totalAlways = "always"
+	totalOnly   = "only" // without the name, and without the inputs
+	totalNever  = "never"
+)
+
+func validTotalMode(mode string) bool {
+	return mode == totalAuto || mode == totalAlways || mode == totalOnly || mode == totalNever
+}
+
+func newFormatter(format string, w io.Writer, orderedFlags []string, width int, totalMode string) (Formatter, error) {
+	switch format {
+	case "text":
+		return &textFormatter{w: w, orderedFlags: orderedFlags, width: width, totalMode: totalMode}, nil
+	case "json":
+		return &jsonFormatter{w: w, metrics: recordMetrics(orderedFlags)}, nil
+	case "ndjson":
+		return &ndjsonFormatter{w: w, metrics: recordMetrics(orderedFlags)}, nil
+	case "csv":
+		return &csvFormatter{w: csv.NewWriter(w), metrics: recordMetrics(orderedFlags)}, nil
+	}
+	return nil, fmt.Errorf("unknown format %q, expected text, json, csv or ndjson", format)
+}
+
+// textFormatter prints aligned columns like GNU wc. Failed inputs are only
+// reported on stderr.
+type textFormatter struct {
+	w            io.Writer
+	orderedFlags []string
+	width        int
+	totalMode    string
+	tables       []frequencyTable
+}
+
+func (f *textFormatter) file(result fileResult) {
+	switch {
+	case f.totalMode == totalOnly:
+	case result.diff != nil:
+		metrics := selectedMetrics(f.orderedFlags)
+		printDiff(f.w, *result.diff, result.diff.status(metrics), metrics, f.width)
+	case result.err == nil:
+		printRow(f.w, columns(result.counts, f.orderedFlags), f.width, result.path)
+	}
+}
+
+func (f *textFormatter) frequencies(tables []frequencyTable) {
+	f.tables = tables
+}
+
+// total prints the total, followed by the line statistics and the frequency
+// tables under a heading
+func (f *textFormatter) total(result fileResult, files int) {
+	counts := result.counts
+	switch {
+	case f.totalMode == totalNever, f.totalMode == totalAuto && files <= 1:
+	case result.diff != nil:
+		printDiff(f.w, *result.diff, fmt.Sprintf("%d files", files), selectedMetrics(f.orderedFlags), f.width)
+	case f.totalMode == totalOnly:
+		printRow(f.w, columns(counts, f.orderedFlags), f.width, "")
+	default:
+		printRow(f.w, columns(counts, f.orderedFlags), f.width, "total")
+	}
+	if counts.Stats != nil {
+		printStats(f.w, counts.Stats)
+	}
+	for _, t := range f.tables {
+		fmt.Fprintf(f.w, "\ntop %ss:\n", t.kind)
+		cells := t.cells()
+		width := f.width
+		for _, cell := range cells {
+			width = max(width, len(cell))
+		}
+		approximate := false
+		for i, e := range t.entries {
+			printRow(f.w, []string{cells[i]}, width, e.Value)
+			approximate = approximate || e.Error > 0
+		}
+		if approximate {
+			fmt.Fprintln(f.w, "(counts shown as a range are approximate, since there were more distinct values than --top-capacity)")
+		}
+	}
+}
+
+func (f *textFormatter) close() error {
+	return nil
+}
+
+// recordMetrics returns the metrics of a machine-readable record: bytes,
+// lines, words and chars always, followed by any other requested metric
+func recordMetrics(orderedFlags []string) []metric {
+	records := metrics[:4:4]
+	for _, f := range orderedFlags {
+		m, ok := findMetric(f)
+		if !ok {
+			continue
+		}
+		known := false
+		for _, r := range records {
+			known = known || r.flag == m.flag
+		}
+		if !known {
+			records = append(records, m)
+		}
+	}
+	return records
+}
+
+type field struct {
+	name  string
+	value any
+}
+
+// record keeps its fields in order when marshalled to JSON
+type record []field
+
+func (r record) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, f := range r {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		name, err := json.Marshal(f.name)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(f.value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// newRecord builds the fields of one input. The metrics of a failed input
+// are null and the error is null when the input was counted.
+func newRecord(path string, counts wc.Result, err error, metrics []metric) record {
+	if path == "" {
+		path = "-"
+	}
+	r := record{{name: "path", value: path}}
+	for _, m := range metrics {
+		var value any
+		if err == nil {
+			value = m.field(counts)
+		}
+		r = append(r, field{name: m.name, value: value})
+	}
+
+	var message any
+	if err != nil {
+		message = reason(err).Error()
+	}
+	return append(r, field{name: "error", value: message})
+}
+
+// fileRecord returns the record of a file, or of a file compared with --diff
+func fileRecord(result fileResult, metrics []metric) record {
+	if result.diff != nil {
+		return diffRecord(*result.diff, metrics)
+	}
+	return newRecord(result.path, result.counts, result.err, metrics)
+}
+
+// totalRecord returns the record of the total, which has the number of files
+// instead of a path
+func totalRecord(result fileResult, files int, metrics []metric) record {
+	if result.diff != nil {
+		return append(record{{name: "files", value: files}}, diffRecord(*result.diff, metrics)[1:]...)
+	}
+	return newTotalRecord(result.counts, files, metrics)
+}
+
+func newTotalRecord(counts wc.Result, files int, metrics []metric) record {
+	r := record{{name: "files", value: files}}
+	for _, m := range metrics {
+		r = append(r, field{name: m.name, value: m.field(counts)})
+	}
+	return r
+}
+
+// jsonFormatter writes a single document with a files array and a total
+// object. Records are written as they come so long lists are streamed.
+type jsonFormatter struct {
+	w       io.Writer
+	metrics []metric
+	files   int
+	tables  []frequencyTable
+	err     error
+}
+
+func (f *jsonFormatter) write(format string, v any) {
+	if f.err != nil {
+		return
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		f.err = err
+		return
+	}
+	_, f.err = fmt.Fprintf(f.w, format, data)
+}
+
+func (f *jsonFormatter) file(result fileResult) {
+	format := ",\n%s"
+	if f.files == 0 {
+		format = "{\"files\":[\n%s"
+	}
+	f.files++
+	f.write(format, fileRecord(result, f.metrics))
+}
+
+func (f *jsonFormatter) frequencies(tables []frequencyTable) {
+	f.tables = tables
+}
+
+// total closes the files array and writes the total, followed by the line
+// statistics and a top_words or top_chars array per frequency table
+func (f *jsonFormatter) total(result fileResult, files int) {
+	format := "\n],\"total\":%s"
+	if f.files == 0 {
+		format = "{\"files\":[],\"total\":%s"
+	}
+	f.write(format, totalRecord(result, files, f.metrics))
+	if stats := result.counts.Stats; stats != nil {
+		f.write(",\"stats\":%s", statsRecord(stats))
+	}
+	for _, t := range f.tables {
+		f.write(",\"top_"+t.kind+"s\":%s", t.records())
+	}
+	if f.err == nil {
+		_, f.err = io.WriteString(f.w, "}\n")
+	}
+}
+
+func (f *jsonFormatter) close() error {
+	return f.err
+}
+
+// ndjsonFormatter writes one JSON object per line, with a type field telling
+// file records from the total record
+type ndjsonFormatter struct {
+	w       io.Writer
+	metrics []metric
+	tables  []frequencyTable
+	err     error
+}
+
+func (f *ndjsonFormatter) write(kind string, r record) {
+	if f.err != nil {
+		return
+	}
+	data, err := json.Marshal(append(record{{name: "type", value: kind}}, r...))
+	if err != nil {
+		f.err = err
+		return
+	}
+	_, f.err = fmt.Fprintf(f.w, "%s\n", data)
+}
+
+func (f *ndjsonFormatter) file(result fileResult) {
+	f.write("file", fileRecord(result, f.metrics))
+}
+
+func (f *ndjsonFormatter) frequencies(tables []frequencyTable) {
+	f.tables = tables
+}
+
+// total writes the line statistics and a word or char object per entry of
+// the frequency tables, followed by the total
+func (f *ndjsonFormatter) total(result fileResult, files int) {
+	if stats := result.counts.Stats; stats != nil {
+		f.write("stats", statsRecord(stats))
+	}
+	for _, t := range f.tables {
+		for _, r := range t.records() {
+			f.write(t.kind, r)
+		}
+	}
+	f.write("total", totalRecord(result, files, f.metrics))
+}
+
+func (f *ndjsonFormatter) close() error {
+	return f.err
+}
+
+// csvFormatter writes a header and one row per input, followed by a total
+// row. The path of the total row is empty. Rows are flushed as they are
+// written, so the updates of a followed file show up at once.
+type csvFormatter struct {
+	w       *csv.Writer
+	metrics []metric
+	header  bool
+}
+
+func (f *csvFormatter) write(kind, path string, values []string, message string) {
+	if !f.header {
+		header := []string{"type", "path"}
+		for _, m := range f.metrics {
+			header = append(header, m.name)
+		}
+		f.w.Write(append(header, "error"))
+		f.header = true
+	}
+
+	row := []string{kind, path}
+	for i := range f.metrics {
+		if values == nil {
+			row = append(row, "")
+			continue
+		}
+		row = append(row, values[i])
+	}
+	f.w.Write(append(row, message))
+	f.w.Flush()
+}
+
+// writeDiff writes a row per metric of a file compared with --diff, under a
+// header of their own
+func (f *csvFormatter) writeDiff(kind string, e diffEntry) {
+	if !f.header {
+		f.w.Write([]string{"type", "path", "status", "metric", "old", "new", "delta", "percent", "error"})
+		f.header = true
+	}
+	for _, row := range diffRows(e, f.metrics) {
+		f.w.Write(append([]string{kind}, row...))
+	}
+	f.w.Flush()
+}
+
+func (f *csvFormatter) values(counts wc.Result) []string {
+	values := make([]string, 0, len(f.metrics))
+	for _, m := range f.metrics {
+		values = append(values, m.text(counts))
+	}
+	return values
+}
+
+func (f *csvFormatter) file(result fileResult) {
+	if result.diff != nil {
+		f.writeDiff("file", *result.diff)
+		return
+	}
+	path := result.path
+	if path == "" {
+		path = "-"
+	}
+	if result.err != nil {
+		f.write("file", path, nil, reason(result.err).Error())
+		return
+	}
+	f.write("file", path, f.values(result.counts), "")
+}
+
+// frequencies ignores the tables, since they do not fit the columns of the
+// other rows
+func (f *csvFormatter) frequencies(tables []frequencyTable) {}
+
+func (f *csvFormatter) total(result fileResult, files int) {
+	if result.diff != nil {
+		e := *result.diff
+		e.name = ""
+		f.writeDiff("total", e)
+		return
+	}
+	f.write("total", "", f.values(result.counts), "")
+}
+
+func (f *csvFormatter) close() error {
+	f.w.Flush()
+	return f.w.Error()
+}
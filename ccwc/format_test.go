@@ -0,0 +1,59 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestMachineReadableFormats(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"a.txt": "hi\n", "m.log": "ERROR a ERROR\nok\nERROR"})
+	a, missing := filepath.Join(dir, "a.txt"), filepath.Join(dir, "missing.txt")
+	log := filepath.Join(dir, "m.log")
+
+	tests := []struct {
+		name   string
+		args   []string
+		stdout string
+	}{
+		{
+			name: "json",
+			args: []string{"--format=json", a, missing},
+			stdout: "{\"files\":[\n" +
+				`{"path":"` + a + `","bytes":3,"lines":1,"words":1,"chars":3,"error":null},` + "\n" +
+				`{"path":"` + missing + `","bytes":null,"lines":null,"words":null,"chars":null,"error":"no such file or directory"}` + "\n" +
+				`],"total":{"files":2,"bytes":3,"lines":1,"words":1,"chars":3}}` + "\n",
+		},
+		{
+			name: "ndjson with an extra metric",
+			args: []string{"--format=ndjson", "-L", a, missing},
+			stdout: `{"type":"file","path":"` + a + `","bytes":3,"lines":1,"words":1,"chars":3,"max_line_length":2,"error":null}` + "\n" +
+				`{"type":"file","path":"` + missing + `","bytes":null,"lines":null,"words":null,"chars":null,"max_line_length":null,"error":"no such file or directory"}` + "\n" +
+				`{"type":"total","files":2,"bytes":3,"lines":1,"words":1,"chars":3,"max_line_length":2}` + "\n",
+		},
+		{
+			name:   "json without files",
+			args:   []string{"--format=json", missing},
+			stdout: "{\"files\":[\n" + `{"path":"` + missing + `","bytes":null,"lines":null,"words":null,"chars":null,"error":"no such file or directory"}` + "\n" + `],"total":{"files":1,"bytes":0,"lines":0,"words":0,"chars":0}}` + "\n",
+		},
+		{
+			name:   "json from stdin",
+			args:   []string{"--format=json"},
+			stdout: "{\"files\":[\n" + `{"path":"-","bytes":3,"lines":1,"words":1,"chars":3,"error":null}` + "\n" + `],"total":{"files":1,"bytes":3,"lines":1,"words":1,"chars":3}}` + "\n",
+		},
+		{
+			name: "json with pattern columns",
+			args: []string{"--format=json", "--count-matches=ERROR", "--count-matching-lines=ERROR", "--count-matches=o.", log},
+			stdout: "{\"files\":[\n" +
+				`{"path":"` + log + `","bytes":22,"lines":2,"words":5,"chars":22,"matches:ERROR":3,"matching_lines:ERROR":2,"matches:o.":1,"error":null}` + "\n" +
+				`],"total":{"files":1,"bytes":22,"lines":2,"words":5,"chars":22,"matches:ERROR":3,"matching_lines:ERROR":2,"matches:o.":1}}` + "\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, stdout, _ := runCCWC(t, "hi\n", tt.args...)
+			if stdout != tt.stdout {
+				t.Errorf("stdout = %q, want %q", stdout, tt.stdout)
+			}
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"io/fs"
+	"os"
+	"regexp"
+	"strings"
+)
+
+type ignoreRule struct {
+	re      *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+// ignoreFile holds the rules of one .gitignore file. Its patterns are
+// relative to dir, the slash-separated path of its directory from the root
+// of the walk.
+type ignoreFile struct {
+	dir   string
+	rules []ignoreRule
+}
+
+// readIgnoreFile parses the .gitignore file at path. It returns nil when
+// there is no such file.
+func readIgnoreFile(path, dir string) (*ignoreFile, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	ignore := &ignoreFile{dir: dir}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if rule, ok := parseIgnoreRule(scanner.Text()); ok {
+			ignore.rules = append(ignore.rules, rule)
+		}
+	}
+	return ignore, scanner.Err()
+}
+
+// parseIgnoreRule compiles one line of a .gitignore file. Blank lines and
+// comments are not rules.
+func parseIgnoreRule(line string) (ignoreRule, bool) {
+	line = strings.TrimSuffix(line, "\r")
+	if !strings.HasSuffix(line, `\ `) {
+		line = strings.TrimRight(line, " ")
+	}
+	if line == "" || line[0] == '#' {
+		return ignoreRule{}, false
+	}
+
+	var rule ignoreRule
+	switch {
+	case line[0] == '!':
+		rule.negate = true
+		line = line[1:]
+	case strings.HasPrefix(line, `\#`), strings.HasPrefix(line, `\!`):
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		rule.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if line == "" {
+		return ignoreRule{}, false
+	}
+
+	// A pattern with a slash before its end is relative to the directory of
+	// the .gitignore file, otherwise it matches a name at any depth
+	anchored := strings.Contains(line, "/")
+	expr := globToRegexp(strings.TrimPrefix(line, "/"))
+	if !anchored {
+		expr = "(?:.*/)?" + expr
+	}
+	re, err := regexp.Compile("^" + expr + "$")
+	if err != nil {
+		return ignoreRule{}, false
+	}
+	rule.re = re
+	return rule, true
+}
+
+// globToRegexp translates a gitignore glob. A * or ? does not match a slash,
+// and ** matches any number of directories.
+func globToRegexp(glob string) string {
+	var b strings.Builder
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch {
+		case strings.HasPrefix(glob[i:], "**/") && (i == 0 || glob[i-1] == '/'):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		case c == '\\' && i+1 < len(glob):
+			i++
+			b.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		case c == '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		default:
+			b.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		}
+	}
+	return b.String()
+}
+
+// ignored reports whether the slash-separated path rel, relative to the root
+// of the walk, is ignored by ignores. The last matching rule wins, and the
+// files are ordered from the root down.
+func ignored(ignores []*ignoreFile, rel string, isDir bool) bool {
+	result := false
+	for _, ignore := range ignores {
+		name := rel
+		if ignore.dir != "" {
+			if !strings.HasPrefix(rel, ignore.dir+"/") {
+				continue
+			}
+			name = rel[len(ignore.dir)+1:]
+		}
+		for _, rule := range ignore.rules {
+			if rule.dirOnly && !isDir {
+				continue
+			}
+			if rule.re.MatchString(name) {
+				result = !rule.negate
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"ccwc/wc"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// noExtension names the group of files without an extension
+const noExtension = "(none)"
+
+// groupKey returns the group of the file at path
+type groupKey func(path string) string
+
+// parseGroupBy returns the group key of spec: ext, dir or depth=N
+func parseGroupBy(spec string) (groupKey, error) {
+	switch spec {
+	case "ext":
+		return extensionKey, nil
+	case "dir":
+		return func(path string) string {
+			return filepath.Dir(path)
+		}, nil
+	}
+
+	if value, ok := strings.CutPrefix(spec, "depth="); ok {
+		depth, err := strconv.Atoi(value)
+		if err != nil || depth < 1 {
+			return nil, fmt.Errorf("invalid depth %q, expected a positive number", value)
+		}
+		return func(path string) string {
+			return depthKey(path, depth)
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown group %q, expected ext, dir or depth=N", spec)
+}
+
+// extensionKey returns the lowercased extension of path. The leading dot of
+// a hidden file such as .gitignore does not start an extension.
+func extensionKey(path string) string {
+	base := filepath.Base(path)
+	ext := strings.ToLower(filepath.Ext(base))
+	if ext == "" || ext == strings.ToLower(base) {
+		return noExtension
+	}
+	return ext
+}
+
+// depthKey returns the directory of path cut to its first depth components,
+// so files deeper in the tree roll up into their ancestor
+func depthKey(path string, depth int) string {
+	dir := filepath.ToSlash(filepath.Dir(path))
+	if dir == "." {
+		return dir
+	}
+	parts := strings.Split(dir, "/")
+	if parts[0] == "" {
+		// The root of an absolute path is not a level of its own
+		depth++
+	}
+	if len(parts) > depth {
+		parts = parts[:depth]
+	}
+	if key := strings.Join(parts, "/"); key != "" {
+		return filepath.FromSlash(key)
+	}
+	return string(filepath.Separator)
+}
+
+// findSortMetric returns the metric named by key, either its field name or
+// its flag. An empty key or name sorts by group name and returns no metric.
+func findSortMetric(key string) (*metric, error) {
+	if key == "" || key == "name" {
+		return nil, nil
+	}
+	for _, m := range metrics {
+		if m.name == key || m.flag == key {
+			return &m, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown sort key %q", key)
+}
+
+type group struct {
+	name   string
+	counts wc.Result
+}
+
+// groupFormatter rolls the counts of every file up into its group, and
+// passes one row per group to the formatter of the output once all files
+// are counted. Failed files are left out of their group.
+type groupFormatter struct {
+	key    groupKey
+	sortBy *metric // sorts by name when nil
+	top    int     // print only the first top groups when positive
+	// newFormatter builds the output formatter, once the width of the
+	// columns is known
+	newFormatter func(width int) Formatter
+	groups       map[string]*group
+	tables       []frequencyTable
+	out          Formatter
+}
+
+func (f *groupFormatter) file(result fileResult) {
+	if result.err != nil {
+		return
+	}
+	name := f.key(result.path)
+	if result.path == "" {
+		name = "-"
+	}
+	g, ok := f.groups[name]
+	if !ok {
+		g = &group{name: name}
+		f.groups[name] = g
+	}
+	g.counts.Add(result.counts)
+}
+
+func (f *groupFormatter) frequencies(tables []frequencyTable) {
+	f.tables = tables
+}
+
+// total prints the groups, sorted by name or by decreasing sortBy, followed
+// by the total of every file
+func (f *groupFormatter) total(result fileResult, files int) {
+	counts := result.counts
+	groups := make([]*group, 0, len(f.groups))
+	for _, g := range f.groups {
+		groups = append(groups, g)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if f.sortBy != nil {
+			a, b := f.sortBy.value(groups[i].counts), f.sortBy.value(groups[j].counts)
+			if a != b {
+				return a > b
+			}
+		}
+		return groups[i].name < groups[j].name
+	})
+	if f.top > 0 && len(groups) > f.top {
+		groups = groups[:f.top]
+	}
+
+	// The total bytes are the largest count of the table, as in numberWidth
+	width := len(strconv.Itoa(counts.Bytes))
+	if width < listWidth {
+		width = listWidth
+	}
+	f.out = f.newFormatter(width)
+	for _, g := range groups {
+		f.out.file(fileResult{path: g.name, counts: g.counts})
+	}
+	f.out.frequencies(f.tables)
+	f.out.total(result, files)
+}
+
+func (f *groupFormatter) close() error {
+	if f.out == nil {
+		return nil
+	}
+	return f.out.close()
+}
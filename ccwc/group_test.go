@@ -0,0 +1,74 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestExtensionKey(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"main.go", ".go"},
+		{"dir/README.MD", ".md"},
+		{"archive.tar.gz", ".gz"},
+		{"Makefile", noExtension},
+		{".gitignore", noExtension},
+		{"dir/.env.local", ".local"},
+		{"/abs/path/x.txt", ".txt"},
+		{"dir.d/file", noExtension},
+	}
+	for _, tt := range tests {
+		if got := extensionKey(filepath.FromSlash(tt.path)); got != tt.want {
+			t.Errorf("extensionKey(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestDepthKey(t *testing.T) {
+	tests := []struct {
+		path  string
+		depth int
+		want  string
+	}{
+		{"file.txt", 1, "."},
+		{"a/file.txt", 1, "a"},
+		{"a/b/c/file.txt", 1, "a"},
+		{"a/b/c/file.txt", 2, "a/b"},
+		{"a/b/file.txt", 5, "a/b"},
+		{"/file.txt", 1, "/"},
+		{"/a/file.txt", 1, "/a"},
+		{"/a/b/c/file.txt", 2, "/a/b"},
+	}
+	for _, tt := range tests {
+		got := depthKey(filepath.FromSlash(tt.path), tt.depth)
+		if got != filepath.FromSlash(tt.want) {
+			t.Errorf("depthKey(%q, %d) = %q, want %q", tt.path, tt.depth, got, tt.want)
+		}
+	}
+}
+
+func TestGroupSortTop(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"a.go":   "one\n",
+		"b.txt":  "one\ntwo\nthree\n",
+		"a/c.md": "1\n2\n",
+		"a/d.go": "x y\n",
+	})
+	var args []string
+	args = append(args, "--group-by=ext", "--sort=lines", "--top=2")
+	for _, name := range []string{"a.go", "b.txt", "a/c.md", "a/d.go"} {
+		args = append(args, filepath.Join(dir, filepath.FromSlash(name)))
+	}
+
+	code, stdout, stderr := runCCWC(t, "", args...)
+	if code != exitOK || stderr != "" {
+		t.Fatalf("exit %d, stderr %q", code, stderr)
+	}
+	// The total still counts the groups cut by --top
+	want := "      3       3      14 .txt\n      2       3       8 .go\n      7       8      26 total\n"
+	if stdout != want {
+		t.Errorf("stdout = %q, want %q", stdout, want)
+	}
+}
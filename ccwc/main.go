@@ -1,203 +1,587 @@
 package main
 
 import (
-	"bufio"
+	"ccwc/wc"
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"os"
-	"reflect"
-	"strings"
+	"os/signal"
+	"regexp"
+	"slices"
+	"syscall"
+	"time"
+)
+
+// Exit codes
+const (
+	exitOK         = 0
+	exitUnreadable = 1 // none of the inputs could be read
+	exitUsage      = 2
+	exitPartial    = 3 // some inputs were counted but others failed
+)
+
+var (
+	errNoInput   = errors.New("no file operand and stdin is a terminal")
+	errEmptyName = errors.New("invalid zero-length file name")
+	errStdinName = errors.New("no file name of '-' allowed when reading file names from standard input")
 )
 
 type Processor interface {
-	countBytes() int
-	countLines() int
-	countWords() int
-	countChars() int
+	count() (wc.Result, error)
 }
 
 type FileProcessor struct {
 	filepath string
+	options  wc.Options
 }
 
 type ContentProcessor struct {
-	content []byte
+	reader  io.Reader
+	options wc.Options
 }
 
-func (fp FileProcessor) countBytes() int {
-	content, err := os.ReadFile(fp.filepath)
-	if err != nil {
-		panic("File not found")
-	}
-	return len(content)
+// documentAuto detects the document of every file by its extension, see
+// fileOptions
+const documentAuto = "auto"
+
+// documentColumns are the columns of every document
+var documentColumns = map[string][]string{
+	wc.DocumentCSV:      {"rows", "columns"},
+	wc.DocumentTSV:      {"rows", "columns"},
+	wc.DocumentJSON:     {"records", "keys"},
+	wc.DocumentNDJSON:   {"records", "keys"},
+	wc.DocumentMarkdown: {"headings", "code-blocks", "links"},
+	documentAuto:        {"rows", "columns", "records", "keys", "headings", "code-blocks", "links"},
 }
 
-func (cp ContentProcessor) countBytes() int {
-	return len(cp.content)
+// fileOptions sets the language of the file at path, which tells its
+// comments from its code, and its document when it is detected
+func fileOptions(options wc.Options, path string) wc.Options {
+	if options.Metrics&wc.MetricCode != 0 {
+		options.Language = wc.LanguageOf(path)
+	}
+	if options.Document == documentAuto {
+		options.Document = wc.DocumentOf(path)
+	}
+	return options
 }
 
-func (fp FileProcessor) countLines() int {
-	var count int
-	file, err := os.Open(fp.filepath)
-	if err != nil {
-		panic("File not found")
+// autoColumns returns the columns of --as=auto in text output. Every row has
+// the same columns, so file operands must all be of documents with the same
+// columns. The files found in directories or read from a list are not known
+// up front, so they get the columns of every document.
+func autoColumns(paths []string, known bool) ([]string, error) {
+	if !known {
+		return documentColumns[documentAuto], nil
 	}
-	defer func() {
-		if err = file.Close(); err != nil {
-			panic("Error closing the file")
+	var columns []string
+	for _, path := range paths {
+		document := wc.DocumentOf(path)
+		if document == "" || columns != nil && !slices.Equal(documentColumns[document], columns) {
+			columns = nil
+			break
 		}
-	}()
-
-	s := bufio.NewScanner(file)
-
-	for s.Scan() {
-		count++
+		columns = documentColumns[document]
 	}
-	if err = s.Err(); err != nil {
-		panic("Error scanning the file")
+	if columns == nil {
+		return nil, errors.New("--as=auto in text output needs file operands whose documents have the same columns, use --as=TYPE or another --format")
 	}
-	return count
-}
-
-func (cp ContentProcessor) countLines() int {
-	lines := strings.Split(string(cp.content), "\n")
-
-	return len(lines)
+	return columns, nil
 }
 
-func (fp FileProcessor) countWords() int {
-	var count int
+func (fp FileProcessor) count() (counts wc.Result, err error) {
 	file, err := os.Open(fp.filepath)
 	if err != nil {
-		panic("File not found")
+		return wc.Result{}, err
 	}
 	defer func() {
-		if err = file.Close(); err != nil {
-			panic("Error closing the file")
+		if closeErr := file.Close(); closeErr != nil && err == nil {
+			err = closeErr
 		}
 	}()
 
-	s := bufio.NewScanner(file)
-
-	for s.Scan() {
-		line := s.Text()
-		words := strings.Fields(line)
-		count += len(words)
-	}
-	if err = s.Err(); err != nil {
-		panic("Error scanning the file")
+	counts, err = wc.CountFile(file, fileOptions(fp.options, fp.filepath))
+	if err != nil {
+		return wc.Result{}, err
 	}
-	return count
+	return counts, nil
 }
 
-func (cp ContentProcessor) countWords() int {
-	words := strings.Fields(string(cp.content))
-	return len(words)
-}
-
-func (fp FileProcessor) countChars() int {
-	content, err := os.ReadFile(fp.filepath)
+func (cp ContentProcessor) count() (wc.Result, error) {
+	counts, err := wc.Count(cp.reader, cp.options)
 	if err != nil {
-		panic("File not found")
+		return wc.Result{}, err
 	}
-	chars := string(content)
-	return len([]rune(chars))
+	return counts, nil
 }
 
-func (cp ContentProcessor) countChars() int {
-	chars := string(cp.content)
-	return len([]rune(chars))
+// reason drops the operation and path from err, since the caller already
+// prints the file name
+func reason(err error) error {
+	var pathErr *os.PathError
+	if errors.As(err, &pathErr) {
+		return pathErr.Err
+	}
+	return err
 }
 
-func main() {
-	var cFlag, lFlag, wFlag, mFlag bool
-	var orderedFlags []string
-	var processor Processor
-	flagSet := flag.NewFlagSet("fs", flag.ContinueOnError)
+func run(args []string, stdin *os.File, stdout, stderr io.Writer) int {
+	var cFlag, lFlag, wFlag, mFlag, maxLineFlag, longestLineFlag, invalidFlag bool
+	var graphemesFlag, unicodeWordsFlag, finalLineFlag, codeFlag bool
+	var sentencesFlag, paragraphsFlag, readabilityFlag bool
+	var document string
+	var recursive, noIgnore, followSymlinks, binaryFlag bool
+	var followFlag, everyInterval bool
+	var compressedFlag, noDecompress, noMmap bool
+	var interval time.Duration
+	var include, exclude globList
+	var files0From, filesFrom, format, encodingName string
+	var groupBy, sortKey string
+	var jobs, top int
+	var topWords, topChars, capacity int
+	var foldCase, stripPunct, statsFlag, diffFlag bool
+	var patterns []*regexp.Regexp
+	var patternKeys []string
+	var helpFlag, versionFlag bool
+	var totalMode string
+	flagSet := flag.NewFlagSet("ccwc", flag.ContinueOnError)
+	flagSet.SetOutput(stderr)
+	flagSet.Usage = func() {
+		fmt.Fprintln(stderr, "Try 'ccwc --help' for more information.")
+	}
 	flagSet.BoolVar(&cFlag, "c", false, "Count the amount of bytes of a file")
 	flagSet.BoolVar(&lFlag, "l", false, "Count the amount of lines of a file")
 	flagSet.BoolVar(&wFlag, "w", false, "Count the amount of words of a file")
 	flagSet.BoolVar(&mFlag, "m", false, "Count the amount of chars of a file")
-	flagSet.Parse(os.Args[1:])
-
-	// Populating orderedFlags
-	for _, f := range os.Args[1:] {
-		switch f {
-		case "-c":
-			if cFlag {
-				orderedFlags = append(orderedFlags, "c")
-			}
-		case "-l":
-			if lFlag {
-				orderedFlags = append(orderedFlags, "l")
-			}
-		case "-m":
-			if mFlag {
-				orderedFlags = append(orderedFlags, "m")
-			}
-		case "-w":
-			if wFlag {
-				orderedFlags = append(orderedFlags, "w")
-			}
+	flagSet.BoolVar(&maxLineFlag, "L", false, "Print the display width of the longest line")
+	flagSet.BoolVar(&longestLineFlag, "longest-line-number", false, "Print the number of the longest line")
+	flagSet.StringVar(&encodingName, "encoding", wc.EncodingAuto, "Decode input as `E`: auto, utf-8, utf-16le, utf-16be, latin1 or windows-1252")
+	flagSet.BoolVar(&invalidFlag, "invalid", false, "Print the number of sequences that are invalid in the input encoding")
+	flagSet.BoolVar(&graphemesFlag, "graphemes", false, "Count extended grapheme clusters as chars")
+	flagSet.BoolVar(&unicodeWordsFlag, "unicode-words", false, "Split words on Unicode word boundaries (UAX #29)")
+	flagSet.BoolVar(&finalLineFlag, "count-final-partial-line", false, "Count a last line without a trailing newline as a line")
+	flagSet.BoolVar(&compressedFlag, "compressed-bytes", false, "Print the size of compressed inputs before decompression")
+	flagSet.BoolVar(&noDecompress, "no-decompress", false, "Count gzip, bzip2 and zlib inputs as they are instead of their content")
+	flagSet.BoolVar(&noMmap, "no-mmap", false, "Read large regular files through a buffer instead of mapping them into memory")
+	flagSet.BoolVar(&codeFlag, "code", false, "Print the code, comment and blank lines of source files, by extension")
+	flagSet.BoolVar(&sentencesFlag, "sentences", false, "Print the number of sentences, which end with '.', '!', '?' or '…' unless after an abbreviation")
+	flagSet.BoolVar(&paragraphsFlag, "paragraphs", false, "Print the number of paragraphs, which are separated by blank lines")
+	flagSet.BoolVar(&readabilityFlag, "readability", false, "Print the Flesch reading ease and the average words per sentence")
+	flagSet.StringVar(&document, "as", "", "Count the structure of inputs as `TYPE`: csv, tsv, json, ndjson, md, or auto to detect it by extension")
+	flagSet.IntVar(&topWords, "top-words", 0, "Print the `N` most frequent words of all inputs")
+	flagSet.IntVar(&topChars, "top-chars", 0, "Print the `N` most frequent chars of all inputs")
+	flagSet.BoolVar(&foldCase, "fold-case", false, "With --top-words or --top-chars, ignore case")
+	flagSet.BoolVar(&stripPunct, "strip-punct", false, "With --top-words or --top-chars, ignore punctuation around words and punctuation chars")
+	flagSet.IntVar(&capacity, "top-capacity", wc.DefaultCapacity, "Track up to `N` distinct words and chars; counts are approximate past that")
+	flagSet.BoolVar(&diffFlag, "diff", false, "Compare the counts of two files, or of the files of two directories, and print the changes")
+	flagSet.BoolVar(&statsFlag, "stats", false, "Print the min, max, mean and median line length and histograms of the line lengths and words per line")
+	flagSet.Var(patternList{kind: matchesKey, patterns: &patterns, keys: &patternKeys}, "count-matches", "Print the number of matches of `regexp` in every line (repeatable)")
+	flagSet.Var(patternList{kind: matchingLinesKey, patterns: &patterns, keys: &patternKeys}, "count-matching-lines", "Print the number of lines matching `regexp` (repeatable)")
+	flagSet.StringVar(&files0From, "files0-from", "", "Read NUL-separated file names from `F` (- for stdin)")
+	flagSet.StringVar(&filesFrom, "files-from", "", "Read newline-separated file names from `F` (- for stdin)")
+	flagSet.StringVar(&format, "format", "text", "Output `format`: text, json, csv or ndjson")
+	flagSet.BoolVar(&recursive, "r", false, "Count the files under directory operands recursively")
+	flagSet.Var(&include, "include", "With -r, only count files matching `glob` (repeatable)")
+	flagSet.Var(&exclude, "exclude", "With -r, skip files and directories matching `glob` (repeatable)")
+	flagSet.BoolVar(&noIgnore, "no-ignore", false, "With -r, also count files ignored by .gitignore files")
+	flagSet.BoolVar(&followSymlinks, "follow-symlinks", false, "With -r, follow symbolic links instead of skipping them")
+	flagSet.BoolVar(&binaryFlag, "binary", false, "With -r, also count files that look binary")
+	flagSet.StringVar(&groupBy, "group-by", "", "Print one row per `group` of files instead of per file: ext, dir or depth=N")
+	flagSet.StringVar(&sortKey, "sort", "", "With --group-by, sort groups by decreasing `metric` (bytes, lines, words, chars...) instead of by name")
+	flagSet.IntVar(&top, "top", 0, "With --group-by, only print the first `N` groups")
+	flagSet.BoolVar(&followFlag, "f", false, "Keep counting a file as it grows and print its counts as they change, until interrupted")
+	flagSet.DurationVar(&interval, "interval", time.Second, "With -f, check the file for new data every `D`")
+	flagSet.BoolVar(&everyInterval, "every-interval", false, "With -f, print the counts at every interval, even when they did not change")
+	flagSet.IntVar(&jobs, "j", 1, "Count up to `N` files or chunks of a large file in parallel")
+	flagSet.StringVar(&totalMode, "total", totalAuto, "`WHEN` to print the total line in text output: auto, always, only or never")
+	flagSet.BoolVar(&helpFlag, "help", false, "Display this help and exit")
+	flagSet.BoolVar(&versionFlag, "version", false, "Output version information and exit")
+
+	normalized, err := normalizeArgs(flagSet, args)
+	if err != nil {
+		fmt.Fprintf(stderr, "ccwc: %v\n", err)
+		flagSet.Usage()
+		return exitUsage
+	}
+	if err := flagSet.Parse(normalized); err != nil {
+		return exitUsage
+	}
+
+	if helpFlag {
+		printUsage(stdout, flagSet)
+		return exitOK
+	}
+	if versionFlag {
+		fmt.Fprintln(stdout, version)
+		return exitOK
+	}
+
+	if _, ok := documentColumns[document]; document != "" && !ok {
+		fmt.Fprintf(stderr, "ccwc: invalid argument '%s' for '--as', expected csv, tsv, json, ndjson, md or auto\n", document)
+		return exitUsage
+	}
+	docColumns := documentColumns[document]
+	if document == documentAuto && format == "text" {
+		known := !recursive && files0From == "" && filesFrom == ""
+		if docColumns, err = autoColumns(flagSet.Args(), known); err != nil {
+			fmt.Fprintf(stderr, "ccwc: %v\n", err)
+			return exitUsage
 		}
 	}
 
-	// Get filepath
-	otherArgs := flagSet.Args()
-	for _, v := range otherArgs {
-		processor = FileProcessor{filepath: v}
-		break
+	// Columns are always printed in the order of GNU wc, followed by the
+	// extra counts and the patterns in the order they were given
+	selected := []struct {
+		set   bool
+		flags []string
+	}{
+		{lFlag, []string{"l"}},
+		{wFlag, []string{"w"}},
+		{mFlag, []string{"m"}},
+		{cFlag, []string{"c"}},
+		{maxLineFlag, []string{"L"}},
+		{longestLineFlag, []string{"longest-line-number"}},
+		{invalidFlag, []string{"invalid"}},
+		{compressedFlag, []string{"compressed-bytes"}},
+		{codeFlag, []string{"code", "comments", "blank"}},
+		{sentencesFlag, []string{"sentences"}},
+		{paragraphsFlag, []string{"paragraphs"}},
+		{readabilityFlag, []string{"readability", "sentence-length"}},
+		{document != "", docColumns},
+	}
+	var orderedFlags []string
+	for _, s := range selected {
+		if s.set {
+			orderedFlags = append(orderedFlags, s.flags...)
+		}
+	}
+	orderedFlags = append(orderedFlags, patternKeys...)
+	// The extra counts are added to the default columns, which are only
+	// replaced by the counts of GNU wc
+	if len(orderedFlags) > 0 && !lFlag && !wFlag && !mFlag && !cFlag && !maxLineFlag {
+		orderedFlags = append(append([]string(nil), defaultFlags...), orderedFlags...)
+	}
+
+	if !validTotalMode(totalMode) {
+		fmt.Fprintf(stderr, "ccwc: invalid argument '%s' for '--total', expected auto, always, only or never\n", totalMode)
+		return exitUsage
 	}
 
-	// If no filepath get content from stdin
-	if processor == nil {
-		stat, err := os.Stdin.Stat()
+	// File names can also come from a list instead of the command line
+	paths := flagSet.Args()
+	listPath, sep := files0From, byte(0)
+	if filesFrom != "" {
+		listPath, sep = filesFrom, '\n'
+	}
+	if files0From != "" && filesFrom != "" {
+		fmt.Fprintln(stderr, "ccwc: --files0-from and --files-from cannot be combined")
+		return exitUsage
+	}
+	if listPath != "" && len(paths) > 0 {
+		fmt.Fprintf(stderr, "ccwc: extra operand '%s': file operands cannot be combined with a list of file names\n", paths[0])
+		return exitUsage
+	}
 
+	encoding, err := wc.ParseEncoding(encodingName)
+	if err != nil {
+		fmt.Fprintf(stderr, "ccwc: %v\n", err)
+		return exitUsage
+	}
+	options := wc.Options{
+		Metrics:               neededMetrics(orderedFlags, format),
+		Encoding:              encoding,
+		Graphemes:             graphemesFlag,
+		UnicodeWords:          unicodeWordsFlag,
+		CountFinalPartialLine: finalLineFlag,
+		Document:              document,
+		Decompress:            !noDecompress,
+		NoMmap:                noMmap,
+		Patterns:              patterns,
+	}
+
+	var key groupKey
+	var sortBy *metric
+	if groupBy != "" {
+		if key, err = parseGroupBy(groupBy); err != nil {
+			fmt.Fprintf(stderr, "ccwc: %v\n", err)
+			return exitUsage
+		}
+		if sortBy, err = findSortMetric(sortKey); err != nil {
+			fmt.Fprintf(stderr, "ccwc: %v\n", err)
+			return exitUsage
+		}
+		if sortBy != nil {
+			options.Metrics |= sortBy.needs
+		}
+	} else if sortKey != "" || top != 0 {
+		fmt.Fprintln(stderr, "ccwc: --sort and --top need --group-by")
+		return exitUsage
+	}
+	if top < 0 {
+		fmt.Fprintf(stderr, "ccwc: invalid number of groups: %d\n", top)
+		return exitUsage
+	}
+
+	if statsFlag {
+		if format == "csv" {
+			fmt.Fprintln(stderr, "ccwc: --stats cannot be combined with --format=csv")
+			return exitUsage
+		}
+		options.Metrics |= wc.MetricStats
+	}
+
+	var frequencies *wc.Frequencies
+	if topWords < 0 || topChars < 0 || capacity < 1 {
+		fmt.Fprintln(stderr, "ccwc: --top-words, --top-chars and --top-capacity need a positive number")
+		return exitUsage
+	}
+	if topWords > 0 || topChars > 0 {
+		if format == "csv" || followFlag {
+			fmt.Fprintln(stderr, "ccwc: --top-words and --top-chars cannot be combined with -f or --format=csv")
+			return exitUsage
+		}
+		frequencies = wc.NewFrequencies(capacity)
+		frequencies.Words = topWords > 0
+		frequencies.Chars = topChars > 0
+		frequencies.FoldCase = foldCase
+		frequencies.StripPunct = stripPunct
+		options.Frequencies = frequencies
+	}
+
+	if followFlag && (len(paths) != 1 || listPath != "" || recursive || key != nil) {
+		fmt.Fprintln(stderr, "ccwc: -f needs exactly one file operand")
+		return exitUsage
+	}
+	if interval <= 0 {
+		fmt.Fprintf(stderr, "ccwc: invalid interval: %v\n", interval)
+		return exitUsage
+	}
+
+	if jobs < 1 {
+		fmt.Fprintf(stderr, "ccwc: invalid number of jobs: %d\n", jobs)
+		return exitUsage
+	}
+
+	// A recursive count defaults to the current directory like grep -r
+	if recursive && listPath == "" && len(paths) == 0 {
+		paths = []string{"."}
+	}
+	walker := Walker{
+		include:        include,
+		exclude:        exclude,
+		gitignore:      !noIgnore,
+		followSymlinks: followSymlinks,
+		binary:         binaryFlag,
+		encoding:       encoding,
+		decompress:     options.Decompress,
+	}
+
+	if diffFlag {
+		if len(paths) != 2 || listPath != "" || followFlag || key != nil || frequencies != nil || statsFlag {
+			fmt.Fprintln(stderr, "ccwc: --diff needs exactly two file or directory operands, and no other mode")
+			return exitUsage
+		}
+
+		pairs, err := diffPairs(paths[0], paths[1], walker)
 		if err != nil {
-			panic("not able to read from stdin")
+			fmt.Fprintf(stderr, "ccwc: %v\n", reason(err))
+			return exitUnreadable
+		}
+		entries := countPairs(pairs, jobs, options)
+		total := diffTotal(entries)
+		formatter, err := newFormatter(format, stdout, orderedFlags, diffWidth(total, selectedMetrics(orderedFlags)), totalMode)
+		if err != nil {
+			fmt.Fprintf(stderr, "ccwc: %v\n", err)
+			return exitUsage
+		}
+		failed := 0
+		for _, e := range entries {
+			if e.err != nil {
+				fmt.Fprintf(stderr, "ccwc: %v\n", e.err)
+				failed++
+			}
+			formatter.file(fileResult{path: e.name, err: e.err, diff: &e})
+		}
+		formatter.total(fileResult{path: total.name, diff: &total}, len(entries))
+		if err := formatter.close(); err != nil {
+			fmt.Fprintf(stderr, "ccwc: write error: %v\n", err)
+			return exitUnreadable
+		}
+		switch {
+		case failed > 0 && failed >= len(entries):
+			return exitUnreadable
+		case failed > 0:
+			return exitPartial
 		}
+		return exitOK
+	}
 
-		if (stat.Mode() & os.ModeCharDevice) == 0 {
-			content, err := io.ReadAll(os.Stdin)
-			if err != nil {
-				panic("not able to read from stdin")
+	// Without file operands the content comes from stdin
+	fromStdin := listPath == "" && len(paths) == 0
+	var listEntries []listEntry
+	listRead := false
+	if listPath != "" {
+		listEntries, listRead = readListUpFront(listPath, sep)
+	}
+	width := listWidth
+	switch {
+	case followFlag:
+		// The file grows, so its current size says little
+		width = numberWidth(paths, stdin, len(columns(wc.Result{}, orderedFlags)))
+		if width < listWidth {
+			width = listWidth
+		}
+	case recursive:
+		// The files found in directories are not known up front either
+	case fromStdin:
+		width = numberWidth(nil, stdin, len(columns(wc.Result{}, orderedFlags)))
+	case listRead:
+		var names []string
+		for _, entry := range listEntries {
+			if entry.err == nil {
+				names = append(names, entry.name)
 			}
-			processor = ContentProcessor{content: content}
 		}
+		width = numberWidth(names, stdin, len(columns(wc.Result{}, orderedFlags)))
+	case listPath == "":
+		width = numberWidth(paths, stdin, len(columns(wc.Result{}, orderedFlags)))
 	}
 
-	if processor == nil {
-		panic("not processor detected")
+	formatter, err := newFormatter(format, stdout, orderedFlags, width, totalMode)
+	if err != nil {
+		fmt.Fprintf(stderr, "ccwc: %v\n", err)
+		return exitUsage
+	}
+	if key != nil {
+		formatter = &groupFormatter{
+			key:    key,
+			sortBy: sortBy,
+			top:    top,
+			newFormatter: func(width int) Formatter {
+				out, _ := newFormatter(format, stdout, orderedFlags, width, totalMode)
+				return out
+			},
+			groups: map[string]*group{},
+		}
 	}
 
-	for _, f := range orderedFlags {
-		switch f {
-		case "c":
-			result := processor.countBytes()
-			fmt.Printf("%d ", result)
-		case "l":
-			result := processor.countLines()
-			fmt.Printf("%d ", result)
-		case "m":
-			result := processor.countChars()
-			fmt.Printf("%d ", result)
-		case "w":
-			result := processor.countWords()
-			fmt.Printf("%d ", result)
+	var total wc.Result
+	var processed, failed int
+	record := func(result fileResult) {
+		processed++
+		formatter.file(result)
+		if result.err != nil {
+			name := result.path
+			if fromStdin {
+				name = "standard input"
+			}
+			fmt.Fprintf(stderr, "ccwc: %s: %v\n", name, reason(result.err))
+			failed++
+			return
 		}
+		total.Add(result.counts)
 	}
 
-	if len(orderedFlags) == 0 {
-		fmt.Printf("%d %d %d ", processor.countBytes(), processor.countLines(), processor.countWords())
+	if fromStdin {
+		stat, err := stdin.Stat()
+		if err != nil {
+			fmt.Fprintf(stderr, "ccwc: standard input: %v\n", reason(err))
+			return exitUnreadable
+		}
+		if (stat.Mode() & os.ModeCharDevice) != 0 {
+			fmt.Fprintf(stderr, "ccwc: %v\n", errNoInput)
+			flagSet.Usage()
+			return exitUsage
+		}
+
+		counts, err := ContentProcessor{reader: stdin, options: options}.count()
+		record(fileResult{counts: counts, err: err})
+	} else if followFlag {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		// Every update is printed, and the last one makes the total
+		follower := &Follower{path: paths[0], options: options, interval: interval, always: everyInterval}
+		err := follower.follow(ctx, func(counts wc.Result) {
+			formatter.file(fileResult{path: paths[0], counts: counts})
+			total = counts
+		})
+		if err != nil {
+			record(fileResult{path: paths[0], err: err})
+		} else {
+			processed++
+		}
+	} else {
+		produce := func(fn func(path string, err error)) error {
+			emit := func(path string) {
+				switch {
+				case path == "-" && listPath == "-":
+					fn(path, errStdinName)
+				case recursive && path != "-":
+					walker.walk(path, fn)
+				default:
+					fn(path, nil)
+				}
+			}
+			if listRead {
+				for _, entry := range listEntries {
+					if entry.err != nil {
+						fn(entry.name, entry.err)
+						continue
+					}
+					emit(entry.name)
+				}
+				return nil
+			}
+			if listPath != "" {
+				return readNamesFrom(listPath, sep, stdin, func(name string, err error) {
+					if err != nil {
+						fn(name, err)
+						return
+					}
+					emit(name)
+				})
+			}
+			for _, path := range paths {
+				emit(path)
+			}
+			return nil
+		}
+
+		if err := countFiles(produce, jobs, options, stdin, record); err != nil {
+			fmt.Fprintf(stderr, "ccwc: %s: %v\n", listPath, reason(err))
+			failed++
+		}
 	}
 
-	processorType := reflect.TypeOf(processor)
-	processorValue := reflect.ValueOf(processor)
+	if frequencies != nil {
+		var tables []frequencyTable
+		if topWords > 0 {
+			tables = append(tables, frequencyTable{kind: "word", entries: frequencies.TopWords(topWords)})
+		}
+		if topChars > 0 {
+			tables = append(tables, frequencyTable{kind: "char", entries: frequencies.TopChars(topChars)})
+		}
+		formatter.frequencies(tables)
+	}
+	formatter.total(fileResult{counts: total}, processed)
+	if err := formatter.close(); err != nil {
+		fmt.Fprintf(stderr, "ccwc: write error: %v\n", err)
+		return exitUnreadable
+	}
 
-	if processorType.Name() == "FileProcessor" {
-		value := processorValue.FieldByName("filepath")
-		fmt.Println(value)
+	switch {
+	case failed > 0 && failed >= processed:
+		return exitUnreadable
+	case failed > 0:
+		return exitPartial
 	}
+	return exitOK
+}
 
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
 }
@@ -0,0 +1,417 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// runCCWC runs a command line with stdin as its standard input, and returns
+// the exit code and what was printed
+func runCCWC(t *testing.T, stdin string, args ...string) (code int, stdout, stderr string) {
+	t.Helper()
+	in, err := os.CreateTemp(t.TempDir(), "stdin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer in.Close()
+	if _, err := in.WriteString(stdin); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := in.Seek(0, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	var out, errOut strings.Builder
+	code = run(args, in, &out, &errOut)
+	return code, out.String(), errOut.String()
+}
+
+// writeFiles creates files with their content in a new directory, and
+// returns the directory
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestRunErrors(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"a.txt": "hi\n"})
+	a := filepath.Join(dir, "a.txt")
+	missing := filepath.Join(dir, "missing.txt")
+
+	tests := []struct {
+		name   string
+		args   []string
+		code   int
+		stdout string
+		stderr string
+	}{
+		{
+			name:   "unknown option",
+			args:   []string{"--bogus", a},
+			code:   exitUsage,
+			stderr: "ccwc: unrecognized option '--bogus'\nTry 'ccwc --help' for more information.\n",
+		},
+		{
+			name:   "invalid short option",
+			args:   []string{"-x", a},
+			code:   exitUsage,
+			stderr: "ccwc: invalid option -- 'x'\nTry 'ccwc --help' for more information.\n",
+		},
+		{
+			name:   "invalid total mode",
+			args:   []string{"--total=sometimes", a},
+			code:   exitUsage,
+			stderr: "ccwc: invalid argument 'sometimes' for '--total', expected auto, always, only or never\n",
+		},
+		{
+			name:   "unknown format",
+			args:   []string{"--format=xml", a},
+			code:   exitUsage,
+			stderr: "ccwc: unknown format \"xml\", expected text, json, csv or ndjson\n",
+		},
+		{
+			name:   "invalid number of jobs",
+			args:   []string{"-j0", a},
+			code:   exitUsage,
+			stderr: "ccwc: invalid number of jobs: 0\n",
+		},
+		{
+			name:   "missing file",
+			args:   []string{missing},
+			code:   exitUnreadable,
+			stderr: "ccwc: " + missing + ": no such file or directory\n",
+		},
+		{
+			name:   "directory",
+			args:   []string{dir},
+			code:   exitUnreadable,
+			stderr: "ccwc: " + dir + ": is a directory\n",
+		},
+		{
+			name:   "some files missing",
+			args:   []string{a, missing},
+			code:   exitPartial,
+			stdout: "1 1 3 " + a + "\n1 1 3 total\n",
+			stderr: "ccwc: " + missing + ": no such file or directory\n",
+		},
+		{
+			name:   "every file counted",
+			args:   []string{a},
+			code:   exitOK,
+			stdout: "1 1 3 " + a + "\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, stdout, stderr := runCCWC(t, "", tt.args...)
+			if code != tt.code {
+				t.Errorf("exit code = %d, want %d", code, tt.code)
+			}
+			if stdout != tt.stdout {
+				t.Errorf("stdout = %q, want %q", stdout, tt.stdout)
+			}
+			if stderr != tt.stderr {
+				t.Errorf("stderr = %q, want %q", stderr, tt.stderr)
+			}
+		})
+	}
+}
+
+func TestRunUnreadableStdin(t *testing.T) {
+	stdin, err := os.Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stdin.Close()
+
+	var stdout, stderr strings.Builder
+	if code := run(nil, stdin, &stdout, &stderr); code != exitUnreadable {
+		t.Errorf("exit code = %d, want %d", code, exitUnreadable)
+	}
+	if want := "ccwc: standard input: is a directory\n"; stderr.String() != want {
+		t.Errorf("stderr = %q, want %q", stderr.String(), want)
+	}
+}
+
+func TestLinesFileAndStdin(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"partial.txt": "one\ntwo",
+		"long.txt":    strings.Repeat("x", 200*1024) + "\n" + strings.Repeat("y", 70*1024),
+	})
+
+	tests := []struct {
+		path    string
+		lines   int
+		partial int // with --count-final-partial-line
+	}{
+		{path: "test.txt", lines: 7145, partial: 7145},
+		{path: "input.txt", lines: 1, partial: 1},
+		{path: filepath.Join(dir, "partial.txt"), lines: 1, partial: 2},
+		{path: filepath.Join(dir, "long.txt"), lines: 1, partial: 2},
+	}
+	for _, tt := range tests {
+		content, err := os.ReadFile(tt.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, mode := range []struct {
+			flags []string
+			want  int
+		}{
+			{[]string{"-l"}, tt.lines},
+			{[]string{"-l", "--count-final-partial-line"}, tt.partial},
+		} {
+			name := filepath.Base(tt.path) + " " + strings.Join(mode.flags, " ")
+			t.Run(name, func(t *testing.T) {
+				want := fmt.Sprintf("%d %s\n", mode.want, tt.path)
+				if _, stdout, _ := runCCWC(t, "", append(mode.flags, tt.path)...); stdout != want {
+					t.Errorf("file: %q, want %q", stdout, want)
+				}
+				want = fmt.Sprintf("%d\n", mode.want)
+				if _, stdout, _ := runCCWC(t, string(content), mode.flags...); stdout != want {
+					t.Errorf("stdin: %q, want %q", stdout, want)
+				}
+			})
+		}
+	}
+}
+
+func TestDecompress(t *testing.T) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	w.Write([]byte("hello world\nbye\n"))
+	w.Close()
+	compressed := buf.String()
+	dir := writeFiles(t, map[string]string{"a.gz": compressed})
+	path := filepath.Join(dir, "a.gz")
+	size := len(compressed)
+
+	tests := []struct {
+		name  string
+		stdin string
+		args  []string
+		want  string
+	}{
+		{"file", "", []string{path}, fmt.Sprintf(" 2  3 16 %s\n", path)},
+		{"stdin", compressed, nil, " 2  3 16\n"},
+		{"compressed bytes", compressed, []string{"-l", "--compressed-bytes"}, fmt.Sprintf(" 2 %d\n", size)},
+		{"no-decompress file", "", []string{"-c", "--no-decompress", path}, fmt.Sprintf("%d %s\n", size, path)},
+		{"no-decompress stdin", compressed, []string{"-c", "--no-decompress"}, fmt.Sprintf("%d\n", size)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, stdout, stderr := runCCWC(t, tt.stdin, tt.args...)
+			if code != exitOK || stdout != tt.want {
+				t.Errorf("exit %d, stdout %q, want %q (stderr %q)", code, stdout, tt.want, stderr)
+			}
+		})
+	}
+}
+
+func TestExtraColumnsKeepDefaults(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"x.go": "package main\n\n// c\nfunc main() {}\n"})
+	path := filepath.Join(dir, "x.go")
+
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"--code", path}, " 4  7 34  2  1  1 " + path + "\n"},
+		{[]string{"--code", "-l", path}, " 4  2  1  1 " + path + "\n"},
+		{[]string{"--invalid", "-w", "-c", path}, " 7 34  0 " + path + "\n"},
+	}
+	for _, tt := range tests {
+		if _, stdout, stderr := runCCWC(t, "", tt.args...); stdout != tt.want {
+			t.Errorf("%v: %q, want %q (stderr %q)", tt.args[:len(tt.args)-1], stdout, tt.want, stderr)
+		}
+	}
+}
+
+func TestTopWordsApproximate(t *testing.T) {
+	_, stdout, _ := runCCWC(t, "a a a a a a b c d e f g\n", "--top-words", "2", "--top-capacity", "2")
+	want := " 1 12 24\n\ntop words:\n  6 a\n1-6 g\n" +
+		"(counts shown as a range are approximate, since there were more distinct values than --top-capacity)\n"
+	if stdout != want {
+		t.Errorf("stdout = %q, want %q", stdout, want)
+	}
+
+	_, stdout, _ = runCCWC(t, "a a b\n", "--top-words", "2")
+	if want := "1 3 6\n\ntop words:\n2 a\n1 b\n"; stdout != want {
+		t.Errorf("exact counts: %q, want %q", stdout, want)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"old/x.txt": "one two\n",
+		"new/x.txt": "one two three\nfour\n",
+	})
+	if err := os.Symlink(filepath.Join(dir, "missing"), filepath.Join(dir, "new", "link.txt")); err != nil {
+		t.Fatal(err)
+	}
+	oldDir, newDir := filepath.Join(dir, "old"), filepath.Join(dir, "new")
+	link := filepath.Join(newDir, "link.txt")
+	changed := "x.txt: changed\n" +
+		"  lines  1  2  +1  +100.0%\n" +
+		"  words  2  4  +2  +100.0%\n" +
+		"  bytes  8 19 +11  +137.5%\n"
+
+	tests := []struct {
+		name   string
+		args   []string
+		code   int
+		stdout string
+		stderr string
+	}{
+		{
+			name: "walker error",
+			args: []string{"--diff", "--follow-symlinks", oldDir, newDir},
+			code: exitPartial,
+			stdout: "link.txt: error\n" + changed + "total: 2 files\n" +
+				"  lines  1  2  +1  +100.0%\n" +
+				"  words  2  4  +2  +100.0%\n" +
+				"  bytes  8 19 +11  +137.5%\n",
+			stderr: "ccwc: " + link + ": no such file or directory\n",
+		},
+		{
+			name:   "without total",
+			args:   []string{"--diff", "--total=never", "--follow-symlinks", oldDir, newDir},
+			code:   exitPartial,
+			stdout: "link.txt: error\n" + changed,
+			stderr: "ccwc: " + link + ": no such file or directory\n",
+		},
+		{
+			name:   "only total",
+			args:   []string{"--diff", "--total=only", "-l", oldDir, newDir},
+			code:   exitOK,
+			stdout: "total: 1 files\n  lines 1 2 +1  +100.0%\n",
+		},
+		{
+			name: "csv",
+			args: []string{"--diff", "--format=csv", "-l", oldDir, newDir},
+			code: exitOK,
+			stdout: "type,path,status,metric,old,new,delta,percent,error\n" +
+				"file,x.txt,changed,bytes,8,19,11,137.50,\n" +
+				"file,x.txt,changed,lines,1,2,1,100.00,\n" +
+				"file,x.txt,changed,words,2,4,2,100.00,\n" +
+				"file,x.txt,changed,chars,8,19,11,137.50,\n" +
+				"total,,changed,bytes,8,19,11,137.50,\n" +
+				"total,,changed,lines,1,2,1,100.00,\n" +
+				"total,,changed,words,2,4,2,100.00,\n" +
+				"total,,changed,chars,8,19,11,137.50,\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, stdout, stderr := runCCWC(t, "", tt.args...)
+			if code != tt.code {
+				t.Errorf("exit code = %d, want %d", code, tt.code)
+			}
+			if stdout != tt.stdout {
+				t.Errorf("stdout = %q, want %q", stdout, tt.stdout)
+			}
+			if stderr != tt.stderr {
+				t.Errorf("stderr = %q, want %q", stderr, tt.stderr)
+			}
+		})
+	}
+}
+
+func TestDocumentColumns(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"a.csv": "a,b\n1,2\n",
+		"b.tsv": "a\tb\tc\n",
+		"c.md":  "# h\n",
+	})
+	csvPath, tsvPath, mdPath := filepath.Join(dir, "a.csv"), filepath.Join(dir, "b.tsv"), filepath.Join(dir, "c.md")
+	list := filepath.Join(t.TempDir(), "list")
+	if err := os.WriteFile(list, []byte(csvPath+"\n"+mdPath+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// Files found by -r or read from a list get the columns of every
+	// document, from rows to links
+	wide := func(name string, values ...int) string {
+		var row strings.Builder
+		for _, v := range values {
+			fmt.Fprintf(&row, "%7d ", v)
+		}
+		return row.String() + name + "\n"
+	}
+
+	tests := []struct {
+		name   string
+		args   []string
+		code   int
+		stdout string
+	}{
+		{
+			name:   "csv keeps the default columns",
+			args:   []string{"--as=csv", csvPath},
+			stdout: "2 2 8 2 2 " + csvPath + "\n",
+		},
+		{
+			name:   "auto prints the columns of the detected documents",
+			args:   []string{"--as=auto", csvPath, tsvPath},
+			stdout: " 2  2  8  2  2 " + csvPath + "\n 1  3  6  1  3 " + tsvPath + "\n 3  5 14  3  3 total\n",
+		},
+		{
+			name: "auto with documents of different columns",
+			args: []string{"--as=auto", csvPath, mdPath},
+			code: exitUsage,
+		},
+		{
+			name: "auto with directories",
+			args: []string{"-r", "--as=auto", dir},
+			stdout: wide(csvPath, 2, 2, 8, 2, 2, 0, 0, 0, 0, 0) +
+				wide(tsvPath, 1, 3, 6, 1, 3, 0, 0, 0, 0, 0) +
+				wide(mdPath, 1, 2, 4, 0, 0, 0, 0, 1, 0, 0) +
+				wide("total", 4, 7, 18, 3, 3, 0, 0, 1, 0, 0),
+		},
+		{
+			name: "auto with a list of files",
+			args: []string{"--as=auto", "--files-from=" + list},
+			stdout: " 2  2  8  2  2  0  0  0  0  0 " + csvPath + "\n" +
+				" 1  2  4  0  0  0  0  1  0  0 " + mdPath + "\n" +
+				" 3  4 12  2  2  0  0  1  0  0 total\n",
+		},
+		{
+			name: "auto without operands",
+			args: []string{"--as=auto"},
+			code: exitUsage,
+		},
+		{
+			name: "auto in another format",
+			args: []string{"--as=auto", "--format=csv", csvPath, mdPath},
+			stdout: "type,path,bytes,lines,words,chars,rows,columns,records,keys,headings,code_blocks,links,error\n" +
+				"file," + csvPath + ",8,2,2,8,2,2,0,0,0,0,0,\n" +
+				"file," + mdPath + ",4,1,2,4,0,0,0,0,1,0,0,\n" +
+				"total,,12,3,4,12,2,2,0,0,1,0,0,\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, stdout, stderr := runCCWC(t, "a,b\n", tt.args...)
+			if code != tt.code {
+				t.Errorf("exit code = %d, want %d (stderr %q)", code, tt.code, stderr)
+			}
+			if stdout != tt.stdout {
+				t.Errorf("stdout = %q, want %q", stdout, tt.stdout)
+			}
+		})
+	}
+}
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"ccwc/wc"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+type metric struct {
+	flag  string    // key in orderedFlags
+	name  string    // field name in machine-readable output
+	needs wc.Metric // what the counter has to compute for it
+	value func(wc.Result) int
+	// score is set for a metric that is not a count, like a readability
+	// score. value is then the score rounded, for sorting and comparing.
+	score func(wc.Result) float64
+}
+
+// scoreMetric returns a metric whose values are not whole numbers
+func scoreMetric(flag, name string, needs wc.Metric, score func(wc.Result) float64) metric {
+	return metric{
+		flag:  flag,
+		name:  name,
+		needs: needs,
+		value: func(c wc.Result) int { return int(math.Round(score(c))) },
+		score: score,
+	}
+}
+
+// text returns the value of m in counts as it is printed in a column
+func (m metric) text(counts wc.Result) string {
+	if m.score != nil {
+		return strconv.FormatFloat(m.score(counts), 'f', 1, 64)
+	}
+	return strconv.Itoa(m.value(counts))
+}
+
+// field returns the value of m in counts for machine-readable output
+func (m metric) field(counts wc.Result) any {
+	if m.score != nil {
+		return m.score(counts)
+	}
+	return m.value(counts)
+}
+
+var metrics = []metric{
+	{flag: "c", name: "bytes", needs: wc.MetricBytes, value: func(c wc.Result) int { return c.Bytes }},
+	{flag: "l", name: "lines", needs: wc.MetricLines, value: func(c wc.Result) int { return c.Lines }},
+	{flag: "w", name: "words", needs: wc.MetricWords, value: func(c wc.Result) int { return c.Words }},
+	{flag: "m", name: "chars", needs: wc.MetricChars, value: func(c wc.Result) int { return c.Chars }},
+	{flag: "L", name: "max_line_length", needs: wc.MetricMaxLineWidth, value: func(c wc.Result) int { return c.MaxLineWidth }},
+	{flag: "longest-line-number", name: "longest_line", needs: wc.MetricLongestLine, value: func(c wc.Result) int { return c.LongestLine }},
+	{flag: "invalid", name: "invalid", needs: wc.MetricInvalid, value: func(c wc.Result) int { return c.Invalid }},
+	{flag: "compressed-bytes", name: "compressed_bytes", needs: wc.MetricBytes, value: func(c wc.Result) int { return c.CompressedBytes }},
+	{flag: "code", name: "code", needs: wc.MetricCode, value: func(c wc.Result) int { return c.Code }},
+	{flag: "comments", name: "comments", needs: wc.MetricCode, value: func(c wc.Result) int { return c.Comments }},
+	{flag: "blank", name: "blank", needs: wc.MetricCode, value: func(c wc.Result) int { return c.Blank }},
+	{flag: "sentences", name: "sentences", needs: wc.MetricProse, value: func(c wc.Result) int { return c.Sentences }},
+	{flag: "paragraphs", name: "paragraphs", needs: wc.MetricProse, value: func(c wc.Result) int { return c.Paragraphs }},
+	{flag: "rows", name: "rows", needs: wc.MetricDocument, value: func(c wc.Result) int { return c.Rows }},
+	{flag: "columns", name: "columns", needs: wc.MetricDocument, value: func(c wc.Result) int { return c.Columns }},
+	{flag: "records", name: "records", needs: wc.MetricDocument, value: func(c wc.Result) int { return c.Records }},
+	{flag: "keys", name: "keys", needs: wc.MetricDocument, value: func(c wc.Result) int { return c.Keys }},
+	{flag: "headings", name: "headings", needs: wc.MetricDocument, value: func(c wc.Result) int { return c.Headings }},
+	{flag: "code-blocks", name: "code_blocks", needs: wc.MetricDocument, value: func(c wc.Result) int { return c.CodeBlocks }},
+	{flag: "links", name: "links", needs: wc.MetricDocument, value: func(c wc.Result) int { return c.Links }},
+	scoreMetric("readability", "flesch_reading_ease", wc.MetricProse|wc.MetricWords, wc.Result.Readability),
+	scoreMetric("sentence-length", "avg_sentence_length", wc.MetricProse|wc.MetricWords, wc.Result.SentenceLength),
+}
+
+// defaultFlags are printed when no metric was requested: lines, words and
+// bytes like GNU wc
+var defaultFlags = []string{"l", "w", "c"}
+
+func findMetric(flag string) (metric, bool) {
+	for _, m := range metrics {
+		if m.flag == flag {
+			return m, true
+		}
+	}
+	return patternMetric(flag)
+}
+
+// Kinds of pattern columns, see patternFlag
+const (
+	matchesKey       = "matches"
+	matchingLinesKey = "matching-lines"
+)
+
+// patternFlags maps the pattern flags to the kind of their columns
+var patternFlags = map[string]string{
+	"count-matches":        matchesKey,
+	"count-matching-lines": matchingLinesKey,
+}
+
+// patternFlag returns the key in orderedFlags of a pattern column. It holds
+// the kind of count, the index of the pattern in wc.Options.Patterns and the
+// pattern itself, as in matches:0:ERROR.
+func patternFlag(kind string, index int, pattern string) string {
+	return fmt.Sprintf("%s:%d:%s", kind, index, pattern)
+}
+
+// patternMetric returns the metric of a key made by patternFlag
+func patternMetric(flag string) (metric, bool) {
+	kind, rest, ok := strings.Cut(flag, ":")
+	if !ok || (kind != matchesKey && kind != matchingLinesKey) {
+		return metric{}, false
+	}
+	indexText, pattern, ok := strings.Cut(rest, ":")
+	index, err := strconv.Atoi(indexText)
+	if !ok || err != nil {
+		return metric{}, false
+	}
+
+	return metric{
+		flag:  flag,
+		name:  strings.ReplaceAll(kind, "-", "_") + ":" + pattern,
+		needs: wc.MetricMatches,
+		value: func(c wc.Result) int {
+			if index >= len(c.Matches) {
+				return 0
+			}
+			if kind == matchesKey {
+				return c.Matches[index].Count
+			}
+			return c.Matches[index].Lines
+		},
+	}, true
+}
+
+// patternList compiles the values of the pattern flags into a single list,
+// in the order they are given, along with the keys of their columns
+type patternList struct {
+	kind     string
+	patterns *[]*regexp.Regexp
+	keys     *[]string
+}
+
+func (p patternList) String() string {
+	if p.patterns == nil {
+		return ""
+	}
+	sources := make([]string, 0, len(*p.patterns))
+	for _, re := range *p.patterns {
+		sources = append(sources, re.String())
+	}
+	return strings.Join(sources, ",")
+}
+
+func (p patternList) Set(pattern string) error {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return err
+	}
+	*p.keys = append(*p.keys, patternFlag(p.kind, len(*p.patterns), pattern))
+	*p.patterns = append(*p.patterns, re)
+	return nil
+}
+
+// columns returns the counts selected by orderedFlags, or bytes, lines and
+// words when no flag was given
+func columns(counts wc.Result, orderedFlags []string) []string {
+	selected := selectedMetrics(orderedFlags)
+	values := make([]string, 0, len(selected))
+	for _, m := range selected {
+		values = append(values, m.text(counts))
+	}
+	return values
+}
+
+// selectedMetrics returns the metrics of the columns of orderedFlags, or of
+// lines, words and bytes when no flag was given
+func selectedMetrics(orderedFlags []string) []metric {
+	if len(orderedFlags) == 0 {
+		orderedFlags = defaultFlags
+	}
+	selected := make([]metric, 0, len(orderedFlags))
+	for _, f := range orderedFlags {
+		if m, ok := findMetric(f); ok {
+			selected = append(selected, m)
+		}
+	}
+	return selected
+}
+
+// neededMetrics returns what the counter has to compute for the columns of
+// orderedFlags, or for every field of a machine-readable format
+func neededMetrics(orderedFlags []string, format string) wc.Metric {
+	selected := orderedFlags
+	if len(selected) == 0 {
+		selected = defaultFlags
+	}
+	var needs wc.Metric
+	for _, f := range selected {
+		if m, ok := findMetric(f); ok {
+			needs |= m.needs
+		}
+	}
+	if format != "text" {
+		for _, m := range recordMetrics(orderedFlags) {
+			needs |= m.needs
+		}
+	}
+	return needs
+}
+
+// numberWidth mirrors GNU wc: columns are as wide as the combined size of the
+// regular files, and at least 7 wide when reading a non-regular file. Without
+// paths, or for the path "-", the file is stdin. A single count of a single
+// input is printed without padding.
+func numberWidth(paths []string, stdin *os.File, columnsLen int) int {
+	if columnsLen == 1 && len(paths) <= 1 {
+		return 1
+	}
+	if len(paths) == 0 {
+		paths = []string{"-"}
+	}
+
+	minWidth := 1
+	var total int64
+	for _, path := range paths {
+		var stat os.FileInfo
+		var err error
+		if path == "-" {
+			stat, err = stdin.Stat()
+		} else {
+			stat, err = os.Stat(path)
+		}
+		if err != nil {
+			continue
+		}
+		if !stat.Mode().IsRegular() {
+			minWidth = 7
+			continue
+		}
+		total += stat.Size()
+	}
+
+	width := len(fmt.Sprint(total))
+	if width < minWidth {
+		width = minWidth
+	}
+	return width
+}
+
+func printRow(w io.Writer, values []string, width int, name string) {
+	fields := make([]string, 0, len(values)+1)
+	for _, v := range values {
+		fields = append(fields, fmt.Sprintf("%*s", width, v))
+	}
+	if name != "" {
+		fields = append(fields, name)
+	}
+	fmt.Fprintln(w, strings.Join(fields, " "))
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"ccwc/wc"
+	"io"
+	"os"
+)
+
+type fileResult struct {
+	path   string
+	counts wc.Result
+	err    error
+	// diff is set instead of counts for the files compared with --diff
+	diff *diffEntry
+}
+
+// ChunkedFileProcessor counts a file by splitting it into chunks that are
+// counted concurrently. At most cap(sem) chunks are counted at the same time.
+type ChunkedFileProcessor struct {
+	filepath string
+	options  wc.Options
+	sem      chan struct{}
+}
+
+// acquire runs task while holding a slot of the semaphore
+func (cp ChunkedFileProcessor) acquire(task func()) {
+	cp.sem <- struct{}{}
+	defer func() { <-cp.sem }()
+	task()
+}
+
+func (cp ChunkedFileProcessor) count() (counts wc.Result, err error) {
+	file, err := os.Open(cp.filepath)
+	if err != nil {
+		return wc.Result{}, err
+	}
+	defer func() {
+		if closeErr := file.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}()
+
+	stat, err := file.Stat()
+	if err != nil {
+		return wc.Result{}, err
+	}
+	options := fileOptions(cp.options, cp.filepath)
+	// Inputs that cannot be split are counted whole, mapped into memory
+	// when they are large
+	if !stat.Mode().IsRegular() || !options.Splittable() {
+		cp.acquire(func() {
+			counts, err = wc.CountFile(file, options)
+		})
+		return counts, err
+	}
+
+	return wc.CountAt(file, stat.Size(), cap(cp.sem), options, cp.acquire)
+}
+
+// countFiles counts every path produced by produce using up to jobs
+// goroutines, and calls record with the results in the order the paths were
+// produced. A path produced with an error is recorded as failed without being
+// counted. The path "-" is read from stdin, as in GNU wc, unless stdin is nil.
+// It returns the error of produce, if any.
+func countFiles(produce func(fn func(path string, err error)) error, jobs int, options wc.Options, stdin io.Reader, record func(fileResult)) error {
+	if jobs == 1 {
+		return produce(func(path string, err error) {
+			switch {
+			case err != nil:
+				record(fileResult{path: path, err: err})
+			case path == "-" && stdin != nil:
+				record(countFile(ContentProcessor{reader: stdin, options: options}, path))
+			default:
+				record(countFile(FileProcessor{filepath: path, options: options}, path))
+			}
+		})
+	}
+
+	sem := make(chan struct{}, jobs)
+	// Bounds how many files are in flight, so long lists are still streamed
+	pending := make(chan chan fileResult, 2*jobs)
+	done := make(chan error, 1)
+	go func() {
+		done <- produce(func(path string, err error) {
+			result := make(chan fileResult, 1)
+			pending <- result
+			switch {
+			case err != nil:
+				result <- fileResult{path: path, err: err}
+				return
+			case path == "-" && stdin != nil:
+				// Counted here, since stdin can only be read by one goroutine
+				// at a time
+				result <- countFile(ContentProcessor{reader: stdin, options: options}, path)
+				return
+			}
+			go func() {
+				result <- countFile(ChunkedFileProcessor{filepath: path, options: options, sem: sem}, path)
+			}()
+		})
+		close(pending)
+	}()
+
+	for result := range pending {
+		record(<-result)
+	}
+	return <-done
+}
+
+func countFile(processor Processor, path string) fileResult {
+	if path == "" {
+		return fileResult{path: path, err: errEmptyName}
+	}
+	counts, err := processor.count()
+	return fileResult{path: path, counts: counts, err: err}
+}
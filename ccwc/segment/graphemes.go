@@ -0,0 +1,139 @@
+// Package segment counts user-perceived characters and words following the
+// boundary rules of Unicode Standard Annex #29. It only depends on the tables
+// of the standard library, so it works offline and without generated data.
+package segment
+
+import "unicode"
+
+type graphemeClass int
+
+const (
+	gAny graphemeClass = iota
+	gCR
+	gLF
+	gControl
+	gExtend
+	gZWJ
+	gRegionalIndicator
+	gSpacingMark
+	gL
+	gV
+	gT
+	gLV
+	gLVT
+	gPictographic
+)
+
+func graphemeClassOf(r rune) graphemeClass {
+	switch {
+	case r == '\r':
+		return gCR
+	case r == '\n':
+		return gLF
+	case r == zwj:
+		return gZWJ
+	case r < 0x20 || (r >= 0x7f && r < 0xa0):
+		return gControl
+	case r < 0x300:
+		if isPictographic(r) {
+			return gPictographic
+		}
+		return gAny
+	case isExtend(r):
+		return gExtend
+	case unicode.In(r, unicode.Zl, unicode.Zp) || (unicode.Is(unicode.Cf, r) && r != zwnj):
+		return gControl
+	case unicode.Is(unicode.Mc, r):
+		return gSpacingMark
+	case isRegionalIndicator(r):
+		return gRegionalIndicator
+	case (r >= 0x1100 && r <= 0x115f) || (r >= 0xa960 && r <= 0xa97c):
+		return gL
+	case (r >= 0x1160 && r <= 0x11a7) || (r >= 0xd7b0 && r <= 0xd7c6):
+		return gV
+	case (r >= 0x11a8 && r <= 0x11ff) || (r >= 0xd7cb && r <= 0xd7fb):
+		return gT
+	case isHangulSyllable(r) && hangulLV(r):
+		return gLV
+	case isHangulSyllable(r):
+		return gLVT
+	case isPictographic(r):
+		return gPictographic
+	}
+	return gAny
+}
+
+// Graphemes counts extended grapheme clusters in a stream of runes. The zero
+// value is ready to use.
+type Graphemes struct {
+	count   int
+	started bool
+	prev    graphemeClass
+	// regional counts the regional indicators in a row, which pair into flags
+	regional int
+	// pictographic is set while the current cluster is a pictograph followed
+	// by extenders, so a ZWJ can join it with the next pictograph
+	pictographic bool
+	zwjSequence  bool
+}
+
+// Add feeds the next rune of the text
+func (g *Graphemes) Add(r rune) {
+	class := graphemeClassOf(r)
+	if !g.started || g.breaks(class) {
+		g.count++
+	}
+	g.started = true
+
+	switch class {
+	case gRegionalIndicator:
+		g.regional++
+	default:
+		g.regional = 0
+	}
+	switch class {
+	case gPictographic:
+		g.pictographic = true
+		g.zwjSequence = false
+	case gExtend:
+	case gZWJ:
+		g.zwjSequence = g.pictographic
+		g.pictographic = false
+	default:
+		g.pictographic = false
+		g.zwjSequence = false
+	}
+	g.prev = class
+}
+
+// breaks reports whether there is a cluster boundary between the previous
+// rune and a rune of class
+func (g *Graphemes) breaks(class graphemeClass) bool {
+	prev := g.prev
+	switch {
+	case prev == gCR && class == gLF: // GB3
+		return false
+	case prev == gCR || prev == gLF || prev == gControl: // GB4
+		return true
+	case class == gCR || class == gLF || class == gControl: // GB5
+		return true
+	case prev == gL && (class == gL || class == gV || class == gLV || class == gLVT): // GB6
+		return false
+	case (prev == gLV || prev == gV) && (class == gV || class == gT): // GB7
+		return false
+	case (prev == gLVT || prev == gT) && class == gT: // GB8
+		return false
+	case class == gExtend || class == gZWJ || class == gSpacingMark: // GB9, GB9a
+		return false
+	case prev == gZWJ && class == gPictographic && g.zwjSequence: // GB11
+		return false
+	case prev == gRegionalIndicator && class == gRegionalIndicator && g.regional%2 == 1: // GB12, GB13
+		return false
+	}
+	return true // GB999
+}
+
+// Count returns the number of clusters seen so far
+func (g *Graphemes) Count() int {
+	return g.count
+}
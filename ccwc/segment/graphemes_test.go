@@ -0,0 +1,43 @@
+package segment
+
+import "testing"
+
+func countGraphemes(text string) int {
+	var g Graphemes
+	for _, r := range text {
+		g.Add(r)
+	}
+	return g.Count()
+}
+
+func TestGraphemes(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want int
+	}{
+		{"empty", "", 0},
+		{"ascii", "abc", 3},
+		{"crlf", "a\r\nb", 3},
+		{"combining acute", "e\u0301", 1},
+		{"several combining marks", "a\u0308\u0301b", 2},
+		{"precomposed", "é", 1},
+		{"cjk", "日本語のテキスト", 8},
+		{"hangul jamo", "\u1100\u1161\u11a8", 1},
+		{"hangul syllables", "한국어", 3},
+		{"devanagari spacing mark", "क\u093f", 1},
+		{"zwj family", "👨\u200d👩\u200d👧\u200d👦", 1},
+		{"zwj with skin tone", "👩\U0001F3FD\u200d💻", 1},
+		{"zwj after letter", "a\u200d👩", 2},
+		{"flags", "🇯🇵🇺🇸", 2},
+		{"odd regional indicators", "🇯🇵🇺", 2},
+		{"emoji and text", "hi 👋\U0001F3FD!", 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := countGraphemes(tt.text); got != tt.want {
+				t.Errorf("graphemes of %q = %d, want %d", tt.text, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,195 @@
+package segment
+
+import (
+	"strings"
+	"unicode"
+)
+
+type wordClass int
+
+const (
+	wNone wordClass = iota // start of text
+	wOther
+	wCR
+	wLF
+	wNewline
+	wExtend
+	wFormat
+	wZWJ
+	wRegionalIndicator
+	wKatakana
+	wALetter
+	wNumeric
+	wExtendNumLet
+	wMidLetter
+	wMidNum
+	wMidNumLet
+	wSingleQuote
+	wSegSpace
+)
+
+const (
+	midLetter = ":··՟״‧︓﹕："
+	midNum    = ",;;։،؍٬߸⁄︐︔﹐﹔，；"
+	midNumLet = ".‘’․﹒＇．"
+)
+
+func isKatakana(r rune) bool {
+	return unicode.Is(unicode.Katakana, r) || r == 0x30fc || r == 0xff70 ||
+		(r >= 0x3031 && r <= 0x3035) || r == 0x309b || r == 0x309c || r == 0x30a0
+}
+
+func wordClassOf(r rune) wordClass {
+	switch {
+	case r == '\r':
+		return wCR
+	case r == '\n':
+		return wLF
+	case r == 0x0b || r == 0x0c || r == 0x85 || r == 0x2028 || r == 0x2029:
+		return wNewline
+	case r == zwj:
+		return wZWJ
+	case r == '\'':
+		return wSingleQuote
+	case strings.ContainsRune(midLetter, r):
+		return wMidLetter
+	case strings.ContainsRune(midNum, r):
+		return wMidNum
+	case strings.ContainsRune(midNumLet, r):
+		return wMidNumLet
+	case unicode.Is(unicode.Zs, r):
+		return wSegSpace
+	case isExtend(r) || unicode.Is(unicode.Mc, r):
+		return wExtend
+	case unicode.Is(unicode.Cf, r) && r != 0x200b:
+		return wFormat
+	case isRegionalIndicator(r):
+		return wRegionalIndicator
+	case isKatakana(r):
+		return wKatakana
+	case unicode.Is(unicode.Nd, r):
+		return wNumeric
+	case unicode.Is(unicode.Pc, r) || r == 0x202f:
+		return wExtendNumLet
+	case unicode.IsLetter(r) && !unicode.In(r, unicode.Ideographic, unicode.Hiragana):
+		return wALetter
+	}
+	return wOther
+}
+
+// isWordLike reports whether a rune makes its segment a word. Segments made
+// only of spaces or punctuation are boundaries, not words. Ideographs and
+// hiragana are words on their own.
+func isWordLike(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsNumber(r)
+}
+
+// Words counts the word segments of a stream of runes. The zero value is
+// ready to use.
+type Words struct {
+	count  int
+	inWord bool // the current segment already counted as a word
+	// prev and beforePrev are the last two classes, skipping extenders and
+	// format characters as rule WB4 requires
+	prev, beforePrev wordClass
+	lastZWJ          bool
+	regional         int
+	// midPending is set when a letter or digit is followed by a mid
+	// punctuation, which only joins the segment if another letter or digit
+	// follows (WB6, WB7, WB11, WB12)
+	midPending bool
+}
+
+// Add feeds the next rune of the text
+func (w *Words) Add(r rune) {
+	class := wordClassOf(r)
+	zwjBefore := w.lastZWJ
+	w.lastZWJ = class == wZWJ
+
+	// WB4: extenders, format characters and ZWJ attach to what precedes them
+	if (class == wExtend || class == wFormat || class == wZWJ) && w.prev != wNone &&
+		w.prev != wCR && w.prev != wLF && w.prev != wNewline {
+		return
+	}
+
+	if w.breaks(class, r, zwjBefore) {
+		w.inWord = false
+	}
+	if isWordLike(r) && !w.inWord {
+		w.count++
+		w.inWord = true
+	}
+
+	if class == wRegionalIndicator {
+		w.regional++
+	} else {
+		w.regional = 0
+	}
+	w.beforePrev, w.prev = w.prev, class
+}
+
+func isAHLetter(c wordClass) bool {
+	return c == wALetter
+}
+
+func isMidNumLetQ(c wordClass) bool {
+	return c == wMidNumLet || c == wSingleQuote
+}
+
+// breaks reports whether there is a word boundary before a rune of class
+func (w *Words) breaks(class wordClass, r rune, zwjBefore bool) bool {
+	prev := w.prev
+	if w.midPending {
+		w.midPending = false
+		joinsLetters := isAHLetter(w.beforePrev) && isAHLetter(class) // WB6, WB7
+		joinsNumbers := w.beforePrev == wNumeric && class == wNumeric // WB11, WB12
+		if joinsLetters || joinsNumbers {
+			return false
+		}
+		// The mid punctuation was a segment on its own after all
+		return true
+	}
+
+	switch {
+	case prev == wNone: // WB1
+		return true
+	case prev == wCR && class == wLF: // WB3
+		return false
+	case prev == wCR || prev == wLF || prev == wNewline: // WB3a
+		return true
+	case class == wCR || class == wLF || class == wNewline: // WB3b
+		return true
+	case zwjBefore && isPictographic(r): // WB3c
+		return false
+	case prev == wSegSpace && class == wSegSpace: // WB3d
+		return false
+	case isAHLetter(prev) && isAHLetter(class): // WB5
+		return false
+	case isAHLetter(prev) && (class == wMidLetter || isMidNumLetQ(class)): // WB6
+		w.midPending = true
+		return false
+	case prev == wNumeric && (class == wMidNum || isMidNumLetQ(class)): // WB12
+		w.midPending = true
+		return false
+	case prev == wNumeric && class == wNumeric: // WB8
+		return false
+	case isAHLetter(prev) && class == wNumeric: // WB9
+		return false
+	case prev == wNumeric && isAHLetter(class): // WB10
+		return false
+	case prev == wKatakana && class == wKatakana: // WB13
+		return false
+	case (isAHLetter(prev) || prev == wNumeric || prev == wKatakana || prev == wExtendNumLet) && class == wExtendNumLet: // WB13a
+		return false
+	case prev == wExtendNumLet && (isAHLetter(class) || class == wNumeric || class == wKatakana): // WB13b
+		return false
+	case prev == wRegionalIndicator && class == wRegionalIndicator && w.regional%2 == 1: // WB15, WB16
+		return false
+	}
+	return true // WB999
+}
+
+// Count returns the number of words seen so far
+func (w *Words) Count() int {
+	return w.count
+}
@@ -0,0 +1,40 @@
+package segment
+
+import "testing"
+
+func countWords(text string) int {
+	var w Words
+	for _, r := range text {
+		w.Add(r)
+	}
+	return w.Count()
+}
+
+func TestWords(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want int
+	}{
+		{"empty", "", 0},
+		{"spaces and punctuation", "  ,.!  ", 0},
+		{"ascii", "Hello, world!", 2},
+		{"apostrophe", "can't stop", 2},
+		{"decimal number", "pi is 3.14", 3},
+		{"trailing period", "end.", 1},
+		{"combining marks", "e\u0301te\u0301 cafe\u0301", 2},
+		{"cjk", "日本語のテキスト", 5},
+		{"cjk in latin", "Go言語 rocks", 4},
+		{"katakana", "テキスト", 1},
+		{"hangul", "한국어 텍스트", 2},
+		{"zwj emoji", "👨\u200d👩\u200d👧 hi", 1},
+		{"newlines", "one\ntwo\r\nthree", 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := countWords(tt.text); got != tt.want {
+				t.Errorf("words of %q = %d, want %d", tt.text, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"ccwc/wc"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+const (
+	// histogramBuckets is the most buckets a histogram is split into
+	histogramBuckets = 10
+	// barWidth is the length of the bar of the largest bucket
+	barWidth = 40
+)
+
+type bucket struct {
+	from, to int // both included
+	lines    int
+}
+
+// histogram groups a distribution into buckets of equal width, covering
+// every value from the smallest to the largest
+func histogram(distribution map[int]int) []bucket {
+	if len(distribution) == 0 {
+		return nil
+	}
+	low, high := -1, 0
+	for value := range distribution {
+		if low < 0 || value < low {
+			low = value
+		}
+		if value > high {
+			high = value
+		}
+	}
+
+	width := (high - low + histogramBuckets) / histogramBuckets
+	buckets := make([]bucket, 0, histogramBuckets)
+	for from := low; from <= high; from += width {
+		buckets = append(buckets, bucket{from: from, to: from + width - 1})
+	}
+	for value, lines := range distribution {
+		buckets[(value-low)/width].lines += lines
+	}
+	buckets[len(buckets)-1].to = high
+	return buckets
+}
+
+// values returns every value of a distribution with its number of lines, in
+// increasing order
+func values(distribution map[int]int) []bucket {
+	buckets := make([]bucket, 0, len(distribution))
+	for value, lines := range distribution {
+		buckets = append(buckets, bucket{from: value, to: value, lines: lines})
+	}
+	sort.Slice(buckets, func(i, j int) bool { return buckets[i].from < buckets[j].from })
+	return buckets
+}
+
+// printStats prints the line statistics under the total, with the line
+// lengths and the words per line as histograms
+func printStats(w io.Writer, s *wc.LineStats) {
+	fmt.Fprintf(w, "\nline length: min %d, max %d, mean %.2f, median %.2f\n",
+		s.MinLength, s.MaxLength, s.Mean(), s.Median())
+	printHistogram(w, histogram(s.Lengths))
+	fmt.Fprintln(w, "\nwords per line:")
+	printHistogram(w, histogram(s.WordsPerLine))
+}
+
+func printHistogram(w io.Writer, buckets []bucket) {
+	most := 0
+	for _, b := range buckets {
+		most = max(most, b.lines)
+	}
+	width := len(fmt.Sprint(most))
+
+	labels := make([]string, len(buckets))
+	labelWidth := 0
+	for i, b := range buckets {
+		labels[i] = fmt.Sprint(b.from)
+		if b.to != b.from {
+			labels[i] = fmt.Sprintf("%d-%d", b.from, b.to)
+		}
+		labelWidth = max(labelWidth, len(labels[i]))
+	}
+
+	for i, b := range buckets {
+		bar := b.lines * barWidth / most
+		if bar == 0 && b.lines > 0 {
+			bar = 1
+		}
+		row := fmt.Sprintf("%*s %*d %s", labelWidth, labels[i], width, b.lines, strings.Repeat("#", bar))
+		fmt.Fprintln(w, strings.TrimRight(row, " "))
+	}
+}
+
+// statsRecord returns the line statistics as a record, with the histogram of
+// the line lengths and every number of words per line
+func statsRecord(s *wc.LineStats) record {
+	lengths := []record{}
+	for _, b := range histogram(s.Lengths) {
+		lengths = append(lengths, record{{name: "from", value: b.from}, {name: "to", value: b.to}, {name: "lines", value: b.lines}})
+	}
+	words := []record{}
+	for _, b := range values(s.WordsPerLine) {
+		words = append(words, record{{name: "words", value: b.from}, {name: "lines", value: b.lines}})
+	}
+
+	return record{
+		{name: "lines", value: s.Lines},
+		{name: "min_length", value: s.MinLength},
+		{name: "max_length", value: s.MaxLength},
+		{name: "mean_length", value: s.Mean()},
+		{name: "median_length", value: s.Median()},
+		{name: "length_histogram", value: lengths},
+		{name: "words_per_line", value: words},
+	}
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"bytes"
+	"ccwc/wc"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// binarySniffSize is how much of a file is read to tell binary files from
+// text, the same amount git uses
+const binarySniffSize = 8000
+
+// globList collects the values of a repeatable glob flag
+type globList []string
+
+func (g *globList) String() string {
+	return strings.Join(*g, ",")
+}
+
+func (g *globList) Set(pattern string) error {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return fmt.Errorf("invalid pattern %q", pattern)
+	}
+	*g = append(*g, pattern)
+	return nil
+}
+
+// matches reports whether a pattern of g matches a file. Patterns with a
+// slash are matched against the path relative to the walked directory, the
+// others against the name of the file.
+func (g globList) matches(rel, name string) bool {
+	for _, pattern := range g {
+		subject := name
+		if strings.Contains(pattern, "/") {
+			subject = rel
+		}
+		if ok, _ := path.Match(pattern, subject); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Walker finds the files to count under the directory operands of -r
+type Walker struct {
+	include        globList
+	exclude        globList
+	gitignore      bool
+	followSymlinks bool
+	binary         bool // count binary files instead of skipping them
+	encoding       string
+	decompress     bool
+}
+
+// walk calls fn with every file to count under root, in lexical order. A root
+// that is not a directory is passed to fn as is. Paths that cannot be read
+// are passed with their error.
+func (w Walker) walk(root string, fn func(path string, err error)) {
+	info, err := os.Stat(root)
+	if err != nil || !info.IsDir() {
+		fn(root, nil)
+		return
+	}
+	w.walkDir(root, "", nil, map[string]bool{}, fn)
+}
+
+func (w Walker) walkDir(dir, rel string, ignores []*ignoreFile, visited map[string]bool, fn func(path string, err error)) {
+	if w.followSymlinks {
+		// Links can make a loop, so every directory is only walked once
+		if real, err := filepath.EvalSymlinks(dir); err == nil {
+			if visited[real] {
+				return
+			}
+			visited[real] = true
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		fn(dir, err)
+	}
+	if w.gitignore {
+		ignore, err := readIgnoreFile(filepath.Join(dir, ".gitignore"), rel)
+		if err != nil {
+			fn(filepath.Join(dir, ".gitignore"), err)
+		}
+		if ignore != nil {
+			ignores = append(ignores[:len(ignores):len(ignores)], ignore)
+		}
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		filePath := filepath.Join(dir, name)
+		relPath := path.Join(rel, name)
+
+		mode := entry.Type()
+		if mode&fs.ModeSymlink != 0 {
+			if !w.followSymlinks {
+				continue
+			}
+			info, err := os.Stat(filePath)
+			if err != nil {
+				fn(filePath, err)
+				continue
+			}
+			mode = info.Mode().Type()
+		}
+
+		if mode.IsDir() {
+			if w.gitignore && name == ".git" {
+				continue
+			}
+			if w.exclude.matches(relPath, name) || (w.gitignore && ignored(ignores, relPath, true)) {
+				continue
+			}
+			w.walkDir(filePath, relPath, ignores, visited, fn)
+			continue
+		}
+
+		// Devices and pipes found in a tree are not meant to be read
+		if !mode.IsRegular() {
+			continue
+		}
+		if len(w.include) > 0 && !w.include.matches(relPath, name) {
+			continue
+		}
+		if w.exclude.matches(relPath, name) || (w.gitignore && ignored(ignores, relPath, false)) {
+			continue
+		}
+		if !w.binary {
+			binary, err := isBinary(filePath, w.encoding, w.decompress)
+			if err != nil {
+				fn(filePath, err)
+				continue
+			}
+			if binary {
+				continue
+			}
+		}
+		fn(filePath, nil)
+	}
+}
+
+// isBinary reports whether the file at path looks binary, that is whether a
+// NUL byte appears in its first bytes. UTF-16 text is full of NUL bytes, so
+// it is never binary, and neither is a compressed file that is decompressed.
+func isBinary(path, encoding string, decompress bool) (bool, error) {
+	if encoding == wc.EncodingUTF16LE || encoding == wc.EncodingUTF16BE {
+		return false, nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+
+	head := make([]byte, binarySniffSize)
+	n, err := io.ReadFull(file, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return false, err
+	}
+	head = head[:n]
+
+	if decompress && wc.IsCompressed(head) {
+		return false, nil
+	}
+	if encoding == wc.EncodingAuto &&
+		(bytes.HasPrefix(head, []byte{0xff, 0xfe}) || bytes.HasPrefix(head, []byte{0xfe, 0xff})) {
+		return false, nil
+	}
+	return bytes.IndexByte(head, 0) >= 0, nil
+}
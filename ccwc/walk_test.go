@@ -0,0 +1,178 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestWalk(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    map[string]string
+		symlinks map[string]string // link name to target
+		walker   Walker
+		want     []string
+	}{
+		{
+			name:   "lexical order",
+			files:  map[string]string{"b.txt": "", "a/z.txt": "", "a/b/c.txt": "", "c.txt": ""},
+			walker: Walker{},
+			want:   []string{"a/b/c.txt", "a/z.txt", "b.txt", "c.txt"},
+		},
+		{
+			name: "gitignore globs and negation",
+			files: map[string]string{
+				".gitignore": "*.log\n!keep.log\n# comment\nbuild/\n/root.txt\ndocs/**/*.tmp\n",
+				"a.log":      "", "keep.log": "", "root.txt": "", "sub/root.txt": "",
+				"build/out.txt": "", "sub/build/out.txt": "",
+				"docs/x.tmp": "", "docs/a/b/y.tmp": "", "docs/z.txt": "",
+			},
+			walker: Walker{gitignore: true},
+			want:   []string{".gitignore", "docs/z.txt", "keep.log", "sub/root.txt"},
+		},
+		{
+			name: "dir-only rule does not match a file",
+			files: map[string]string{
+				".gitignore": "out/\n",
+				"out":        "", "sub/out/x.txt": "",
+			},
+			walker: Walker{gitignore: true},
+			want:   []string{".gitignore", "out"},
+		},
+		{
+			name: "nested gitignore is relative to its directory",
+			files: map[string]string{
+				".gitignore":     "*.tmp\n",
+				"sub/.gitignore": "/local.txt\n!x.tmp\n",
+				"local.txt":      "", "sub/local.txt": "", "sub/deeper/local.txt": "",
+				"a.tmp": "", "sub/x.tmp": "", "sub/y.tmp": "",
+			},
+			walker: Walker{gitignore: true},
+			want:   []string{".gitignore", "local.txt", "sub/.gitignore", "sub/deeper/local.txt", "sub/x.tmp"},
+		},
+		{
+			name: "character classes and escapes",
+			files: map[string]string{
+				".gitignore": "file[0-9].txt\n\\#hash\nq?.txt\n",
+				"file1.txt":  "", "fileA.txt": "", "#hash": "", "qa.txt": "", "qaa.txt": "",
+			},
+			walker: Walker{gitignore: true},
+			want:   []string{".gitignore", "fileA.txt", "qaa.txt"},
+		},
+		{
+			name:   "no-ignore",
+			files:  map[string]string{".gitignore": "*.log\n", "a.log": "", ".git/config": ""},
+			walker: Walker{},
+			want:   []string{".git/config", ".gitignore", "a.log"},
+		},
+		{
+			name:   "include and exclude by name",
+			files:  map[string]string{"a.go": "", "a_test.go": "", "sub/b.go": "", "c.txt": ""},
+			walker: Walker{include: globList{"*.go"}, exclude: globList{"*_test.go"}},
+			want:   []string{"a.go", "sub/b.go"},
+		},
+		{
+			name:   "include and exclude by path",
+			files:  map[string]string{"a.go": "", "sub/b.go": "", "sub/c.go": "", "vendor/d.go": ""},
+			walker: Walker{include: globList{"sub/*.go"}, exclude: globList{"sub/c.go"}},
+			want:   []string{"sub/b.go"},
+		},
+		{
+			name:   "excluded directory",
+			files:  map[string]string{"a.go": "", "vendor/d.go": "", "x/vendor/e.go": ""},
+			walker: Walker{exclude: globList{"vendor"}},
+			want:   []string{"a.go"},
+		},
+		{
+			name: "binary files",
+			files: map[string]string{
+				"bin.dat":   "a\x00b",
+				"utf16.txt": "\xff\xfea\x00",
+				"text.txt":  "text",
+			},
+			walker: Walker{encoding: "auto"},
+			want:   []string{"text.txt", "utf16.txt"},
+		},
+		{
+			name:   "binary files counted",
+			files:  map[string]string{"bin.dat": "a\x00b", "text.txt": "text"},
+			walker: Walker{binary: true},
+			want:   []string{"bin.dat", "text.txt"},
+		},
+		{
+			name:     "symlinks skipped",
+			files:    map[string]string{"a/x.txt": ""},
+			symlinks: map[string]string{"link.txt": "a/x.txt", "b": "a"},
+			walker:   Walker{},
+			want:     []string{"a/x.txt"},
+		},
+		{
+			name:     "symlinks followed, each directory once",
+			files:    map[string]string{"a/x.txt": ""},
+			symlinks: map[string]string{"link.txt": "a/x.txt", "a/loop": ".."},
+			walker:   Walker{followSymlinks: true},
+			want:     []string{"a/x.txt", "link.txt"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := writeFiles(t, tt.files)
+			for name, target := range tt.symlinks {
+				if err := os.Symlink(target, filepath.Join(root, name)); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			var got []string
+			tt.walker.walk(root, func(path string, err error) {
+				if err != nil {
+					t.Errorf("%s: %v", path, err)
+					return
+				}
+				rel, err := filepath.Rel(root, path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, filepath.ToSlash(rel))
+			})
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("walk = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGlobToRegexp(t *testing.T) {
+	tests := []struct {
+		glob  string
+		match []string
+		miss  []string
+	}{
+		{"*.go", []string{"a.go", ".go"}, []string{"a/b.go", "a.gox"}},
+		{"a?c", []string{"abc"}, []string{"a/c", "ac"}},
+		{"**/x", []string{"x", "a/x", "a/b/x"}, []string{"ax"}},
+		{"a/**", []string{"a/b", "a/b/c"}, []string{"b/a"}},
+		{"a/**/b", []string{"a/b", "a/x/b", "a/x/y/b"}, []string{"ab"}},
+		{"[!a]x", []string{"bx"}, []string{"ax"}},
+		{`\*`, []string{"*"}, []string{"a"}},
+		{"a.b", []string{"a.b"}, []string{"axb"}},
+	}
+	for _, tt := range tests {
+		rule, ok := parseIgnoreRule("/" + tt.glob)
+		if !ok {
+			t.Fatalf("%q is not a rule", tt.glob)
+		}
+		for _, name := range tt.match {
+			if !rule.re.MatchString(name) {
+				t.Errorf("%q does not match %q", tt.glob, name)
+			}
+		}
+		for _, name := range tt.miss {
+			if rule.re.MatchString(name) {
+				t.Errorf("%q matches %q", tt.glob, name)
+			}
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package wc
+
+import (
+	"io"
+	"unicode/utf8"
+)
+
+// MinChunkSize is the smallest chunk CountAt splits an input into, since
+// smaller chunks cost more in goroutines than they save
+const MinChunkSize = 1 << 20
+
+type chunkResult struct {
+	engine *engine
+	err    error
+}
+
+// CountAt counts the first size bytes of r, split into up to parts chunks
+// that are counted concurrently. Every chunk is counted inside a call to do,
+// which runs the task it is given and returns once it is done, so callers can
+// bound how many tasks run at the same time.
+//
+// Inputs that are too small, not UTF-8, compressed or counted with options
+// that are not Splittable are counted whole in a single task.
+func CountAt(r io.ReaderAt, size int64, parts int, options Options, do func(task func())) (Result, error) {
+	prefix := make([]byte, magicSize)
+	n, err := r.ReadAt(prefix, 0)
+	if err != nil && err != io.EOF {
+		return Result{}, err
+	}
+
+	utf8Input := detectEncoding(prefix[:n], options.Encoding) == EncodingUTF8
+	compressed := options.Decompress && IsCompressed(prefix[:n])
+	if parts < 2 || size < 2*MinChunkSize || !utf8Input || compressed || !options.Splittable() {
+		var result Result
+		do(func() {
+			result, err = Count(io.NewSectionReader(r, 0, size), options)
+		})
+		return result, err
+	}
+
+	offsets, err := chunkOffsets(r, size, parts)
+	if err != nil {
+		return Result{}, err
+	}
+
+	results := make([]chan chunkResult, len(offsets)-1)
+	for i := range results {
+		results[i] = make(chan chunkResult, 1)
+		go do(func() {
+			e := newEngine(options)
+			buf := make([]byte, bufferSize)
+			section := io.NewSectionReader(r, offsets[i], offsets[i+1]-offsets[i])
+			_, err := io.CopyBuffer(e, section, buf)
+			results[i] <- chunkResult{engine: e, err: err}
+		})
+	}
+
+	chunks := make([]*engine, len(results))
+	for i, result := range results {
+		r := <-result
+		if r.err != nil && err == nil {
+			err = r.err
+		}
+		chunks[i] = r.engine
+	}
+	if err != nil {
+		return Result{}, err
+	}
+	counts := mergeChunks(chunks)
+	counts.CompressedBytes = counts.Bytes
+	return options.finishLines(counts), nil
+}
+
+// mergeChunks combines the engines of consecutive chunks of one input. A word
+// that straddles two chunks is counted once.
+func mergeChunks(chunks []*engine) Result {
+	var counts Result
+	for i, c := range chunks {
+		result := c.result(0)
+		counts.Add(result)
+		if i > 0 && chunks[i-1].endsInWord() && c.startsInWord() {
+			counts.Words--
+		}
+		if result.Bytes > 0 {
+			counts.PartialLine = result.PartialLine
+		}
+	}
+	return counts
+}
+
+// chunkOffsets splits size bytes into at most parts chunks of at least
+// MinChunkSize bytes. It returns the boundaries, starting with 0 and ending
+// with size.
+func chunkOffsets(r io.ReaderAt, size int64, parts int) ([]int64, error) {
+	if max := int(size / MinChunkSize); parts > max {
+		parts = max
+	}
+
+	offsets := []int64{0}
+	for i := 1; i < parts; i++ {
+		offset, err := alignToRune(r, size*int64(i)/int64(parts))
+		if err != nil {
+			return nil, err
+		}
+		if offset > offsets[len(offsets)-1] && offset < size {
+			offsets = append(offsets, offset)
+		}
+	}
+	return append(offsets, size), nil
+}
+
+// alignToRune moves offset forward past UTF-8 continuation bytes, so no rune
+// is split between two chunks. A rune has at most 3 continuation bytes.
+func alignToRune(r io.ReaderAt, offset int64) (int64, error) {
+	buf := make([]byte, utf8.UTFMax-1)
+	n, err := r.ReadAt(buf, offset)
+	if err != nil && err != io.EOF {
+		return 0, err
+	}
+	for _, b := range buf[:n] {
+		if utf8.RuneStart(b) {
+			break
+		}
+		offset++
+	}
+	return offset, nil
+}
@@ -0,0 +1,268 @@
+package wc
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// Quote is a string delimiter of a language
+type Quote struct {
+	Delim string
+	// Escapes is set when a backslash escapes the next character
+	Escapes bool
+	// Multiline is set when the string can span several lines
+	Multiline bool
+}
+
+// Language describes the comment and string syntax of a programming
+// language, which is enough to tell code lines from comment lines
+type Language struct {
+	Name          string
+	Extensions    []string
+	LineComments  []string
+	BlockComments [][2]string // start and end markers
+	// Quotes are tried in order, so longer delimiters come first
+	Quotes []Quote
+	// CommentAfterSpace is set when a line comment marker only starts a
+	// comment at the start of a word, as # in shell and YAML
+	CommentAfterSpace bool
+}
+
+var (
+	doubleQuote = Quote{Delim: `"`, Escapes: true}
+	singleQuote = Quote{Delim: `'`, Escapes: true}
+)
+
+var languages = []Language{
+	{
+		Name:          "Go",
+		Extensions:    []string{".go"},
+		LineComments:  []string{"//"},
+		BlockComments: [][2]string{{"/*", "*/"}},
+		Quotes:        []Quote{doubleQuote, singleQuote, {Delim: "`", Multiline: true}},
+	},
+	{
+		Name:         "Python",
+		Extensions:   []string{".py", ".pyw"},
+		LineComments: []string{"#"},
+		Quotes: []Quote{
+			{Delim: `"""`, Escapes: true, Multiline: true},
+			{Delim: `'''`, Escapes: true, Multiline: true},
+			doubleQuote, singleQuote,
+		},
+	},
+	{
+		Name:          "JavaScript",
+		Extensions:    []string{".js", ".mjs", ".cjs", ".jsx", ".ts", ".tsx"},
+		LineComments:  []string{"//"},
+		BlockComments: [][2]string{{"/*", "*/"}},
+		Quotes:        []Quote{doubleQuote, singleQuote, {Delim: "`", Escapes: true, Multiline: true}},
+	},
+	{
+		Name:          "C",
+		Extensions:    []string{".c", ".h", ".cc", ".cpp", ".cxx", ".hh", ".hpp"},
+		LineComments:  []string{"//"},
+		BlockComments: [][2]string{{"/*", "*/"}},
+		Quotes:        []Quote{doubleQuote, singleQuote},
+	},
+	{
+		Name:              "Shell",
+		Extensions:        []string{".sh", ".bash", ".zsh", ".ksh"},
+		LineComments:      []string{"#"},
+		Quotes:            []Quote{doubleQuote, {Delim: `'`, Multiline: true}},
+		CommentAfterSpace: true,
+	},
+	{
+		Name:              "YAML",
+		Extensions:        []string{".yaml", ".yml"},
+		LineComments:      []string{"#"},
+		Quotes:            []Quote{doubleQuote, {Delim: `'`}},
+		CommentAfterSpace: true,
+	},
+}
+
+// LanguageOf returns the language of a file from the extension of its name,
+// or nil when it is not a known language
+func LanguageOf(name string) *Language {
+	ext := strings.ToLower(filepath.Ext(name))
+	for i := range languages {
+		for _, e := range languages[i].Extensions {
+			if e == ext {
+				return &languages[i]
+			}
+		}
+	}
+	return nil
+}
+
+// markerLookahead is how many bytes past the current one a marker can
+// span, since the longest marker is 3 bytes long
+const markerLookahead = 2
+
+// codeLines classifies every line as code, comment or blank. A line with
+// any code is a code line, even when it also has a comment. Without a
+// language every line that is not blank is code.
+type codeLines struct {
+	language *Language
+	code     int
+	comments int
+	blank    int
+
+	line   []byte // the part of the current line not scanned yet
+	prev   byte   // the byte before line[0]
+	inLine bool   // the current line is not empty
+	// hasCode and hasComment describe the current line so far
+	hasCode, hasComment bool
+	lineComment         bool   // in a comment that ends with the line
+	blockEnd            string // end marker of the block comment the scan is in
+	quote               *Quote // the string the scan is in
+}
+
+func newCodeLines(language *Language) *codeLines {
+	return &codeLines{language: language, prev: '\n'}
+}
+
+func (c *codeLines) add(r rune) {
+	if r == '\n' {
+		c.scan(true)
+		c.endLine()
+		return
+	}
+	c.inLine = true
+	c.line = utf8.AppendRune(c.line, r)
+	if len(c.line) >= bufferSize {
+		c.scan(false)
+	}
+}
+
+// scan classifies the bytes of the current line. Unless final, the last
+// bytes are kept until the next call, since a marker may start there.
+func (c *codeLines) scan(final bool) {
+	end := len(c.line)
+	if !final {
+		end -= markerLookahead
+	}
+
+	i := 0
+	for i < end {
+		n := c.step(c.line[i:])
+		c.prev = c.line[i+n-1]
+		i += n
+	}
+	if final {
+		c.line = c.line[:0]
+		return
+	}
+	c.line = c.line[:copy(c.line, c.line[i:])]
+}
+
+// step scans the start of p and returns how many bytes it consumed
+func (c *codeLines) step(p []byte) int {
+	b := p[0]
+	space := isSpaceByte(b)
+	switch {
+	case c.lineComment:
+		if !space {
+			c.hasComment = true
+		}
+		return 1
+	case c.blockEnd != "":
+		if bytes.HasPrefix(p, []byte(c.blockEnd)) {
+			n := len(c.blockEnd)
+			c.blockEnd = ""
+			c.hasComment = true
+			return n
+		}
+		if !space {
+			c.hasComment = true
+		}
+		return 1
+	case c.quote != nil:
+		c.hasCode = c.hasCode || !space
+		if c.quote.Escapes && b == '\\' && len(p) > 1 {
+			return 2
+		}
+		if bytes.HasPrefix(p, []byte(c.quote.Delim)) {
+			n := len(c.quote.Delim)
+			c.quote = nil
+			return n
+		}
+		return 1
+	case space:
+		return 1
+	case c.language == nil:
+		c.hasCode = true
+		return 1
+	}
+
+	for _, marker := range c.language.LineComments {
+		if !bytes.HasPrefix(p, []byte(marker)) {
+			continue
+		}
+		if c.language.CommentAfterSpace && !isSpaceByte(c.prev) {
+			continue
+		}
+		c.lineComment = true
+		c.hasComment = true
+		return len(marker)
+	}
+	for _, block := range c.language.BlockComments {
+		if bytes.HasPrefix(p, []byte(block[0])) {
+			c.blockEnd = block[1]
+			c.hasComment = true
+			return len(block[0])
+		}
+	}
+	for i := range c.language.Quotes {
+		if q := &c.language.Quotes[i]; bytes.HasPrefix(p, []byte(q.Delim)) {
+			c.quote = q
+			c.hasCode = true
+			return len(q.Delim)
+		}
+	}
+	c.hasCode = true
+	return 1
+}
+
+func isSpaceByte(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\r' || b == '\f' || b == '\v' || b == '\n'
+}
+
+// endLine counts the current line and starts the next one. Strings that
+// cannot span lines end with it, like comments that run to its end.
+func (c *codeLines) endLine() {
+	switch {
+	case c.hasCode:
+		c.code++
+	case c.hasComment:
+		c.comments++
+	default:
+		c.blank++
+	}
+	c.inLine, c.hasCode, c.hasComment, c.lineComment = false, false, false, false
+	if c.quote != nil && !c.quote.Multiline {
+		c.quote = nil
+	}
+	c.prev = '\n'
+}
+
+// clone returns a copy of c that does not share its line buffer
+func (c *codeLines) clone() *codeLines {
+	clone := *c
+	clone.line = append([]byte(nil), c.line...)
+	return &clone
+}
+
+// counts returns the code, comment and blank lines so far. An unterminated
+// last line is counted, without changing the state of c.
+func (c *codeLines) counts() (code, comments, blank int) {
+	if !c.inLine {
+		return c.code, c.comments, c.blank
+	}
+	last := c.clone()
+	last.scan(true)
+	last.endLine()
+	return last.code, last.comments, last.blank
+}
@@ -0,0 +1,99 @@
+package wc
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCodeLines(t *testing.T) {
+	tests := []struct {
+		name     string
+		file     string
+		input    string
+		code     int
+		comments int
+		blank    int
+	}{
+		{
+			name:     "go",
+			file:     "x.go",
+			input:    "// doc\npackage x\n\n/* block\n   still */\nvar a = 1 // trailing\n",
+			code:     2,
+			comments: 3,
+			blank:    1,
+		},
+		{
+			name:  "go raw string",
+			file:  "x.go",
+			input: "var s = `\n// not a comment\n/* nor this\n`\n",
+			code:  4,
+		},
+		{
+			name:  "go rune and string",
+			file:  "x.go",
+			input: "c := '\"'\ns := \"// \\\" /*\"\n",
+			code:  2,
+		},
+		{
+			name:     "python triple quotes",
+			file:     "x.py",
+			input:    "# comment\ns = \"\"\"\n# not a comment\n\"\"\"\nx = 1  # trailing\n",
+			code:     4,
+			comments: 1,
+		},
+		{
+			name:     "shell hash inside a word",
+			file:     "x.sh",
+			input:    "echo a#b\n# comment\necho ${#x} # trailing\n",
+			code:     2,
+			comments: 1,
+		},
+		{
+			name:  "shell single quotes span lines",
+			file:  "x.sh",
+			input: "echo 'a\n# not a comment\n'\n",
+			code:  3,
+		},
+		{
+			name:     "yaml quoted hash",
+			file:     "x.yaml",
+			input:    "# comment\nkey: \"a # b\"\nother: 'c # d'\nurl: http://x/#frag\n",
+			code:     3,
+			comments: 1,
+		},
+		{
+			name:     "c quote in a char literal",
+			file:     "x.c",
+			input:    "char q = '\"'; // comment\n/* c */\nint x;\n",
+			code:     2,
+			comments: 1,
+		},
+		{
+			name:     "javascript template literal",
+			file:     "x.js",
+			input:    "const s = `\n// not a comment\n`;\n// comment\n",
+			code:     3,
+			comments: 1,
+		},
+		{
+			name:  "unknown language",
+			file:  "x.txt",
+			input: "# text\n\nmore\n",
+			code:  2,
+			blank: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := Options{Metrics: MetricCode, Language: LanguageOf(tt.file)}
+			got, err := Count(strings.NewReader(tt.input), options)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Code != tt.code || got.Comments != tt.comments || got.Blank != tt.blank {
+				t.Errorf("code, comments, blank = %d, %d, %d, want %d, %d, %d",
+					got.Code, got.Comments, got.Blank, tt.code, tt.comments, tt.blank)
+			}
+		})
+	}
+}
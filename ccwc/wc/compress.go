@@ -0,0 +1,89 @@
+package wc
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+)
+
+// magicSize is how many bytes are needed to recognize a compressed input
+const magicSize = 4
+
+const (
+	formatNone = iota
+	formatGzip
+	formatBzip2
+	formatZlib
+)
+
+// compressionOf recognizes gzip, bzip2 and zlib from their magic bytes. Only
+// the zlib headers of the usual compression levels are recognized, since any
+// two bytes can be a valid zlib header and "x^" starts plenty of text.
+func compressionOf(prefix []byte) int {
+	switch {
+	case bytes.HasPrefix(prefix, []byte{0x1f, 0x8b}):
+		return formatGzip
+	case len(prefix) >= 4 && bytes.HasPrefix(prefix, []byte("BZh")) && prefix[3] >= '1' && prefix[3] <= '9':
+		return formatBzip2
+	case len(prefix) >= 2 && prefix[0] == 0x78 && (prefix[1] == 0x01 || prefix[1] == 0x9c || prefix[1] == 0xda):
+		return formatZlib
+	}
+	return formatNone
+}
+
+// IsCompressed reports whether an input starting with prefix is compressed
+// in a format Count can decompress
+func IsCompressed(prefix []byte) bool {
+	return compressionOf(prefix) != formatNone
+}
+
+// countingReader counts the bytes read through it
+type countingReader struct {
+	r io.Reader
+	n int
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += n
+	return n, err
+}
+
+// decompressor returns a reader of the content of r, compressed in format
+func decompressor(format int, r io.Reader) (io.Reader, error) {
+	switch format {
+	case formatGzip:
+		return gzip.NewReader(r)
+	case formatBzip2:
+		return bzip2.NewReader(r), nil
+	case formatZlib:
+		return zlib.NewReader(r)
+	}
+	return r, nil
+}
+
+// countCompressed counts r, decompressing it when it is compressed. The
+// CompressedBytes of the result are the bytes read from r.
+func countCompressed(r io.Reader, options Options) (Result, error) {
+	raw := &countingReader{r: r}
+	buffered := bufio.NewReaderSize(raw, bufferSize)
+	// A shorter input is not compressed, and is still counted
+	prefix, err := buffered.Peek(magicSize)
+	if err != nil && err != io.EOF {
+		return Result{}, err
+	}
+	content, err := decompressor(compressionOf(prefix), buffered)
+	if err != nil {
+		return Result{}, err
+	}
+
+	result, err := count(content, options)
+	if err != nil {
+		return Result{}, err
+	}
+	result.CompressedBytes = raw.n
+	return result, nil
+}
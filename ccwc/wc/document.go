@@ -0,0 +1,332 @@
+package wc
+
+import (
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// Documents are the structured formats counted for MetricDocument
+const (
+	DocumentCSV      = "csv"
+	DocumentTSV      = "tsv"
+	DocumentJSON     = "json"
+	DocumentNDJSON   = "ndjson"
+	DocumentMarkdown = "md"
+)
+
+// documentExtensions maps file extensions to their documents
+var documentExtensions = map[string]string{
+	".csv":      DocumentCSV,
+	".tsv":      DocumentTSV,
+	".tab":      DocumentTSV,
+	".json":     DocumentJSON,
+	".ndjson":   DocumentNDJSON,
+	".jsonl":    DocumentNDJSON,
+	".md":       DocumentMarkdown,
+	".markdown": DocumentMarkdown,
+}
+
+// DocumentOf returns the document of a file from the extension of its name,
+// or "" when it is not a known document
+func DocumentOf(name string) string {
+	return documentExtensions[strings.ToLower(filepath.Ext(name))]
+}
+
+// documentTracker counts the structure of a document
+type documentTracker interface {
+	add(r rune)
+	// result sets the counts of the document so far in counts, including an
+	// unterminated last line, without changing the state of the tracker
+	result(counts *Result)
+}
+
+func newDocumentTracker(document string) documentTracker {
+	switch document {
+	case DocumentCSV:
+		return &csvTracker{separator: ','}
+	case DocumentTSV:
+		return &csvTracker{separator: '\t'}
+	case DocumentJSON, DocumentNDJSON:
+		return &jsonTracker{}
+	case DocumentMarkdown:
+		return &markdownTracker{}
+	}
+	return nil
+}
+
+// csvTracker counts the rows of a CSV or TSV document and the fields of its
+// widest row. Fields can be quoted with double quotes, so a quoted separator
+// or newline does not split them, and empty lines are not rows.
+type csvTracker struct {
+	separator rune
+	rows      int
+	columns   int
+
+	fields     int // in the current row, when it is not empty
+	fieldStart bool
+	inQuotes   bool
+	afterQuote bool // the last rune closed a quoted field
+}
+
+func (t *csvTracker) add(r rune) {
+	if t.fields == 0 {
+		if r == '\n' {
+			return
+		}
+		t.fields, t.fieldStart = 1, true
+	}
+
+	switch {
+	case r == '"' && t.inQuotes:
+		t.inQuotes, t.afterQuote = false, true
+		return
+	case r == '"' && (t.fieldStart || t.afterQuote):
+		// A doubled quote inside a quoted field escapes a quote
+		t.inQuotes = true
+	case t.inQuotes:
+	case r == t.separator:
+		t.fields++
+		t.fieldStart = true
+		t.afterQuote = false
+		return
+	case r == '\n':
+		t.endRow()
+		return
+	}
+	t.fieldStart, t.afterQuote = false, false
+}
+
+func (t *csvTracker) endRow() {
+	t.rows++
+	t.columns = max(t.columns, t.fields)
+	t.fields, t.inQuotes, t.afterQuote = 0, false, false
+}
+
+func (t *csvTracker) result(counts *Result) {
+	end := *t
+	if end.fields > 0 {
+		end.endRow()
+	}
+	counts.Rows, counts.Columns = end.rows, end.columns
+}
+
+// jsonTracker counts the records of JSON and NDJSON documents and the keys
+// of their objects at every depth. Every value at the top level is a record,
+// except for an array, whose elements are the records. Invalid JSON is
+// counted as far as it makes sense.
+type jsonTracker struct {
+	records int
+	keys    int
+
+	containers []rune // the open arrays and objects, as '[' or '{'
+	rootArray  bool   // the current top-level value is an array
+	expectKey  bool   // the next string of the object is a key
+	inString   bool
+	escaped    bool
+	inScalar   bool // in a number, true, false or null
+}
+
+func (t *jsonTracker) add(r rune) {
+	if t.inString {
+		switch {
+		case t.escaped:
+			t.escaped = false
+		case r == '\\':
+			t.escaped = true
+		case r == '"':
+			t.inString = false
+		}
+		return
+	}
+
+	switch {
+	case r == '{' || r == '[':
+		t.inScalar = false
+		t.startValue(r == '[')
+		t.containers = append(t.containers, r)
+		t.expectKey = r == '{'
+	case r == '}' || r == ']':
+		t.inScalar = false
+		if len(t.containers) > 0 {
+			t.containers = t.containers[:len(t.containers)-1]
+		}
+		t.expectKey = false
+	case r == ',':
+		t.inScalar = false
+		t.expectKey = t.inObject()
+	case r == ':':
+		t.inScalar = false
+	case unicode.IsSpace(r):
+		t.inScalar = false
+	case r == '"':
+		t.inScalar = false
+		t.inString = true
+		if t.expectKey && t.inObject() {
+			t.keys++
+			t.expectKey = false
+			return
+		}
+		t.startValue(false)
+	case !t.inScalar:
+		t.inScalar = true
+		t.startValue(false)
+	}
+}
+
+func (t *jsonTracker) inObject() bool {
+	return len(t.containers) > 0 && t.containers[len(t.containers)-1] == '{'
+}
+
+// startValue counts a value that starts at the current depth when it is a
+// record
+func (t *jsonTracker) startValue(array bool) {
+	switch {
+	case len(t.containers) == 0 && array:
+		t.rootArray = true
+	case len(t.containers) == 0:
+		t.rootArray = false
+		t.records++
+	case len(t.containers) == 1 && t.rootArray:
+		t.records++
+	}
+}
+
+func (t *jsonTracker) result(counts *Result) {
+	counts.Records, counts.Keys = t.records, t.keys
+}
+
+// markdownTracker counts the headings, fenced code blocks and links of a
+// Markdown document. Headings are ATX headings, as in "## Title", and setext
+// headings, which are a line of text underlined with = or -. Links are
+// inline links and images, as in [text](url), and autolinks, as in
+// <https://example.com>. Nothing is counted inside code blocks and code
+// spans.
+type markdownTracker struct {
+	headings   int
+	codeBlocks int
+	links      int
+
+	line  []rune // the current line, without its newline
+	fence string // the fence of the open code block, as ``` or ~~~~
+	// paragraph is set when the last line is text that a setext underline
+	// turns into a heading
+	paragraph bool
+}
+
+func (t *markdownTracker) add(r rune) {
+	if r != '\n' {
+		t.line = append(t.line, r)
+		return
+	}
+	t.endLine()
+	t.line = t.line[:0]
+}
+
+func (t *markdownTracker) endLine() {
+	line := strings.TrimRight(string(t.line), " \t\r")
+	indent := len(line) - len(strings.TrimLeft(line, " "))
+	text := strings.TrimLeft(line, " ")
+
+	if t.fence != "" {
+		if indent < 4 && strings.HasPrefix(text, t.fence) && strings.Trim(text, t.fence[:1]) == "" {
+			t.fence = ""
+		}
+		return
+	}
+	if indent >= 4 {
+		t.paragraph = t.paragraph && text != ""
+		return
+	}
+	if fence := fenceOf(text); fence != "" {
+		t.codeBlocks++
+		t.fence, t.paragraph = fence, false
+		return
+	}
+
+	switch {
+	case text == "":
+		t.paragraph = false
+	case atxHeading(text):
+		t.headings++
+		t.links += countLinks(text)
+		t.paragraph = false
+	case t.paragraph && (strings.Trim(text, "=") == "" || strings.Trim(text, "-") == ""):
+		t.headings++
+		t.paragraph = false
+	default:
+		t.links += countLinks(text)
+		t.paragraph = true
+	}
+}
+
+// fenceOf returns the fence a line opens a code block with, or ""
+func fenceOf(text string) string {
+	for _, c := range []string{"`", "~"} {
+		n := len(text) - len(strings.TrimLeft(text, c))
+		if n >= 3 && !(c == "`" && strings.Contains(text[n:], "`")) {
+			return text[:n]
+		}
+	}
+	return ""
+}
+
+// atxHeading reports whether text is a heading of 1 to 6 #
+func atxHeading(text string) bool {
+	n := len(text) - len(strings.TrimLeft(text, "#"))
+	return n >= 1 && n <= 6 && (len(text) == n || text[n] == ' ' || text[n] == '\t')
+}
+
+// countLinks counts the inline links, images and autolinks of a line of
+// text, outside of code spans
+func countLinks(text string) int {
+	links := 0
+	inCode := false
+	brackets := 0 // [ opened and not closed yet
+	for i := 0; i < len(text); i++ {
+		switch c := text[i]; {
+		case c == '`':
+			inCode = !inCode
+		case inCode:
+		case c == '\\':
+			i++
+		case c == '[':
+			brackets++
+		case c == ']' && brackets > 0:
+			brackets--
+			if rest := text[i+1:]; strings.HasPrefix(rest, "(") && strings.Contains(rest, ")") {
+				links++
+			}
+		case c == '<':
+			if end := strings.IndexByte(text[i:], '>'); end > 0 && isAutolink(text[i+1:i+end]) {
+				links++
+				i += end
+			}
+		}
+	}
+	return links
+}
+
+// isAutolink reports whether the text between < and > is an absolute URI or
+// an email address
+func isAutolink(text string) bool {
+	if strings.ContainsAny(text, " <") {
+		return false
+	}
+	scheme, _, ok := strings.Cut(text, ":")
+	if ok && len(scheme) >= 2 && strings.IndexFunc(scheme, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && !strings.ContainsRune("+.-", r)
+	}) < 0 {
+		return true
+	}
+	at := strings.IndexByte(text, '@')
+	return at > 0 && strings.Contains(text[at:], ".")
+}
+
+func (t *markdownTracker) result(counts *Result) {
+	end := *t
+	if len(end.line) > 0 {
+		end.endLine()
+	}
+	counts.Headings, counts.CodeBlocks, counts.Links = end.headings, end.codeBlocks, end.links
+}
@@ -0,0 +1,40 @@
+package wc
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestJSONDocument(t *testing.T) {
+	tests := []struct {
+		name     string
+		document string
+		input    string
+		records  int
+		keys     int
+	}{
+		{"object", DocumentJSON, `{"a": 1, "b": "x"}`, 1, 2},
+		{"array of records", DocumentJSON, `[{"a": 1}, {"b": 2, "c": [1, 2]}]`, 2, 3},
+		{"array of scalars", DocumentJSON, `[1, 2.5, "three", null]`, 4, 0},
+		{"empty array", DocumentJSON, `[]`, 0, 0},
+		{"nested keys", DocumentJSON, `{"a": {"b": {"c": 1}}, "d": [{"e": 2}, {"f": 3}]}`, 1, 6},
+		{"escaped quotes", DocumentJSON, `{"a\"b": "c\", \"d:", "e": "\\"}`, 1, 2},
+		{"brackets in strings", DocumentJSON, `{"a": "{[,:", "b": "]}"}`, 1, 2},
+		{"scalars at the top level", DocumentJSON, "1 \"x\" true", 3, 0},
+		{"ndjson", DocumentNDJSON, "{\"a\": 1}\n{\"a\": 2, \"b\": 3}\n", 2, 3},
+		{"ndjson with arrays", DocumentNDJSON, "[1, 2]\n{\"a\": [3]}\n", 3, 1},
+		{"unterminated", DocumentJSON, `[{"a": 1}, {"b": `, 2, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := Options{Metrics: MetricDocument, Document: tt.document}
+			got, err := Count(strings.NewReader(tt.input), options)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Records != tt.records || got.Keys != tt.keys {
+				t.Errorf("records, keys = %d, %d, want %d, %d", got.Records, got.Keys, tt.records, tt.keys)
+			}
+		})
+	}
+}
@@ -0,0 +1,188 @@
+package wc
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Encodings of Options.Encoding, as returned by ParseEncoding
+const (
+	EncodingAuto        = "auto"
+	EncodingUTF8        = "utf-8"
+	EncodingUTF16LE     = "utf-16le"
+	EncodingUTF16BE     = "utf-16be"
+	EncodingLatin1      = "latin1"
+	EncodingWindows1252 = "windows-1252"
+)
+
+var (
+	bomUTF16LE = []byte{0xff, 0xfe}
+	bomUTF16BE = []byte{0xfe, 0xff}
+)
+
+// ParseEncoding normalizes an encoding name, accepting the usual aliases
+func ParseEncoding(name string) (string, error) {
+	switch strings.ToLower(name) {
+	case "", EncodingAuto:
+		return EncodingAuto, nil
+	case EncodingUTF8, "utf8":
+		return EncodingUTF8, nil
+	case EncodingUTF16LE, "utf16le":
+		return EncodingUTF16LE, nil
+	case EncodingUTF16BE, "utf16be":
+		return EncodingUTF16BE, nil
+	case EncodingLatin1, "latin-1", "iso-8859-1":
+		return EncodingLatin1, nil
+	case EncodingWindows1252, "cp1252":
+		return EncodingWindows1252, nil
+	}
+	return "", fmt.Errorf("unknown encoding %q", name)
+}
+
+// detectEncoding resolves auto from the byte order mark at the start of the
+// input, which is not counted. Inputs without one are read as UTF-8, and a
+// UTF-8 byte order mark is counted like any other character.
+func detectEncoding(prefix []byte, encoding string) string {
+	if encoding != EncodingAuto && encoding != "" {
+		return encoding
+	}
+	switch {
+	case bytes.HasPrefix(prefix, bomUTF16LE):
+		return EncodingUTF16LE
+	case bytes.HasPrefix(prefix, bomUTF16BE):
+		return EncodingUTF16BE
+	}
+	return EncodingUTF8
+}
+
+// byteOrderMark returns the byte order mark of a UTF-16 encoding, or nil
+func byteOrderMark(encoding string) []byte {
+	switch encoding {
+	case EncodingUTF16LE:
+		return bomUTF16LE
+	case EncodingUTF16BE:
+		return bomUTF16BE
+	}
+	return nil
+}
+
+// invalidByte replaces a sequence a decoder cannot decode. It is never valid
+// UTF-8, so the engine counts it as invalid like the bytes of UTF-8 input.
+const invalidByte = 0xff
+
+// decoder converts its input to UTF-8 before it is counted
+type decoder interface {
+	// decode appends the UTF-8 encoding of p to dst, with an invalidByte for
+	// every sequence that cannot be decoded. An incomplete sequence at the
+	// end of p is kept until the next call.
+	decode(dst, p []byte) []byte
+	// trailing returns the invalid sequences an incomplete sequence kept at
+	// the end of the input amounts to
+	trailing() int
+}
+
+// newDecoder returns the decoder of encoding, or nil for UTF-8 which is
+// counted as is
+func newDecoder(encoding string) decoder {
+	switch encoding {
+	case EncodingUTF16LE:
+		return &utf16Decoder{bigEndian: false}
+	case EncodingUTF16BE:
+		return &utf16Decoder{bigEndian: true}
+	case EncodingLatin1:
+		return &singleByteDecoder{}
+	case EncodingWindows1252:
+		return &singleByteDecoder{table: &windows1252}
+	}
+	return nil
+}
+
+type utf16Decoder struct {
+	bigEndian bool
+	odd       []byte // a byte left over from an odd sized write
+	high      rune   // a high surrogate waiting for its pair
+}
+
+func (d *utf16Decoder) decode(dst, p []byte) []byte {
+	if len(d.odd) > 0 && len(p) > 0 {
+		dst = d.decodeUnit(dst, d.odd[0], p[0])
+		d.odd = d.odd[:0]
+		p = p[1:]
+	}
+	for ; len(p) >= 2; p = p[2:] {
+		dst = d.decodeUnit(dst, p[0], p[1])
+	}
+	if len(p) == 1 {
+		d.odd = append(d.odd, p[0])
+	}
+	return dst
+}
+
+func (d *utf16Decoder) decodeUnit(dst []byte, a, b byte) []byte {
+	unit := rune(a) | rune(b)<<8
+	if d.bigEndian {
+		unit = rune(a)<<8 | rune(b)
+	}
+
+	if d.high != 0 {
+		high := d.high
+		d.high = 0
+		if r := utf16.DecodeRune(high, unit); r != utf8.RuneError {
+			return utf8.AppendRune(dst, r)
+		}
+		dst = append(dst, invalidByte)
+	}
+	switch {
+	case unit >= 0xd800 && unit < 0xdc00:
+		d.high = unit
+	case unit >= 0xdc00 && unit < 0xe000:
+		dst = append(dst, invalidByte)
+	default:
+		dst = utf8.AppendRune(dst, unit)
+	}
+	return dst
+}
+
+func (d *utf16Decoder) trailing() int {
+	if d.high != 0 || len(d.odd) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// singleByteDecoder maps every byte to one rune. Bytes below 0x80 are ASCII
+// and bytes above 0x9f are Latin-1 in every supported code page, so a table
+// only describes 0x80 to 0x9f. Without a table the input is Latin-1.
+type singleByteDecoder struct {
+	table *[32]rune
+}
+
+func (d *singleByteDecoder) decode(dst, p []byte) []byte {
+	for _, b := range p {
+		r := rune(b)
+		if d.table != nil && b >= 0x80 && b < 0xa0 {
+			r = d.table[b-0x80]
+			if r == utf8.RuneError {
+				dst = append(dst, invalidByte)
+				continue
+			}
+		}
+		dst = utf8.AppendRune(dst, r)
+	}
+	return dst
+}
+
+func (d *singleByteDecoder) trailing() int {
+	return 0
+}
+
+// windows1252 maps 0x80 to 0x9f. Undefined bytes decode to utf8.RuneError.
+var windows1252 = [32]rune{
+	0x20ac, utf8.RuneError, 0x201a, 0x0192, 0x201e, 0x2026, 0x2020, 0x2021,
+	0x02c6, 0x2030, 0x0160, 0x2039, 0x0152, utf8.RuneError, 0x017d, utf8.RuneError,
+	utf8.RuneError, 0x2018, 0x2019, 0x201c, 0x201d, 0x2022, 0x2013, 0x2014,
+	0x02dc, 0x2122, 0x0161, 0x203a, 0x0153, utf8.RuneError, 0x017e, 0x0178,
+}
@@ -0,0 +1,266 @@
+package wc
+
+import (
+	"bytes"
+	"ccwc/segment"
+	"unicode"
+	"unicode/utf8"
+)
+
+var newline = []byte{'\n'}
+
+// engine computes every metric of UTF-8 text in a single pass. It implements
+// io.Writer so the input can be streamed into it in chunks of any size.
+type engine struct {
+	counts  Result
+	inWord  bool
+	pending []byte // incomplete UTF-8 sequence carried over from the previous chunk
+	// firstInWord reports whether the first rune was part of a word, which is
+	// needed to merge the counts of consecutive chunks
+	firstInWord bool
+	linePos     int // display width of the current line so far
+	graphemes   *segment.Graphemes
+	words       *segment.Words
+	code        *codeLines
+	freq        *frequencyTracker
+	matcher     *lineMatcher
+	stats       *lineStatsTracker
+	prose       *proseTracker
+	document    documentTracker
+	// lineWidths is set when the line widths are needed, since measuring
+	// every rune is the slowest part of a pass
+	lineWidths bool
+	// linesOnly is set when neither runes nor words are needed, so the input
+	// does not have to be decoded at all
+	linesOnly bool
+	// swar is set when only the counts of lines, words and chars are needed,
+	// so runs of ASCII can be counted 8 bytes at a time
+	swar bool
+}
+
+func newEngine(options Options) *engine {
+	c := &engine{
+		lineWidths: options.wants(MetricMaxLineWidth | MetricLongestLine),
+		linesOnly: !options.wants(MetricWords|MetricChars|MetricMaxLineWidth|MetricLongestLine|MetricInvalid|MetricCode|MetricMatches|MetricStats|MetricProse|MetricDocument) &&
+			!options.Graphemes && !options.UnicodeWords && options.Frequencies == nil,
+	}
+	if options.Graphemes {
+		c.graphemes = &segment.Graphemes{}
+	}
+	if options.UnicodeWords {
+		c.words = &segment.Words{}
+	}
+	if options.wants(MetricCode) {
+		c.code = newCodeLines(options.Language)
+	}
+	if options.Frequencies != nil {
+		c.freq = newFrequencyTracker(options.Frequencies)
+	}
+	if len(options.Patterns) > 0 && options.wants(MetricMatches) {
+		c.matcher = newLineMatcher(options.Patterns)
+	}
+	if options.wants(MetricStats) {
+		c.stats = newLineStatsTracker()
+	}
+	if options.wants(MetricProse) {
+		c.prose = newProseTracker()
+	}
+	if options.wants(MetricDocument) {
+		c.document = newDocumentTracker(options.Document)
+	}
+	c.swar = !c.lineWidths && c.graphemes == nil && c.words == nil && c.code == nil &&
+		c.freq == nil && c.matcher == nil && c.stats == nil && c.prose == nil && c.document == nil
+	return c
+}
+
+func (c *engine) Write(p []byte) (int, error) {
+	n := len(p)
+	if n == 0 {
+		return 0, nil
+	}
+	c.counts.Bytes += n
+	c.counts.PartialLine = p[n-1] != '\n'
+	if c.linesOnly {
+		c.counts.Lines += bytes.Count(p, newline)
+		return n, nil
+	}
+
+	// Complete a rune split across the previous chunk boundary
+	for len(c.pending) > 0 && len(p) > 0 {
+		c.pending = append(c.pending, p[0])
+		p = p[1:]
+		if utf8.FullRune(c.pending) {
+			carried := c.pending
+			c.pending = nil
+			c.consume(carried)
+		}
+	}
+
+	c.consume(p)
+	return n, nil
+}
+
+// consume counts the runes in p, keeping a trailing incomplete rune as pending
+func (c *engine) consume(p []byte) {
+	for len(p) > 0 {
+		if c.swar && len(p) >= 8 {
+			if n := c.scanASCII(p); n > 0 {
+				p = p[n:]
+				continue
+			}
+		}
+		b := p[0]
+		if b < utf8.RuneSelf {
+			c.countRune(rune(b), false)
+			p = p[1:]
+			continue
+		}
+		if !utf8.FullRune(p) {
+			c.pending = append(c.pending, p...)
+			return
+		}
+		r, size := utf8.DecodeRune(p)
+		c.countRune(r, r == utf8.RuneError && size == 1)
+		p = p[size:]
+	}
+}
+
+// countRune counts r, or a byte that is not valid UTF-8 when invalid is set.
+// Like in GNU wc, an invalid byte is neither a char nor has a width, but it
+// is part of a word.
+func (c *engine) countRune(r rune, invalid bool) {
+	space := unicode.IsSpace(r)
+	if c.empty() {
+		c.firstInWord = !space
+	}
+	if invalid {
+		c.counts.Invalid++
+	} else {
+		c.counts.Chars++
+		if c.lineWidths {
+			c.trackWidth(r)
+		}
+		if c.graphemes != nil {
+			c.graphemes.Add(r)
+		}
+	}
+	if c.words != nil {
+		c.words.Add(r)
+	}
+	if c.code != nil {
+		c.code.add(r)
+	}
+	if c.freq != nil {
+		c.freq.add(r, space)
+	}
+	if c.matcher != nil {
+		c.matcher.add(r)
+	}
+	if c.stats != nil {
+		c.stats.add(r, space)
+	}
+	if c.prose != nil {
+		c.prose.add(r, space)
+	}
+	if c.document != nil {
+		c.document.add(r)
+	}
+	if r == '\n' {
+		c.counts.Lines++
+	}
+	if space {
+		c.inWord = false
+		return
+	}
+	if !c.inWord {
+		c.counts.Words++
+		c.inWord = true
+	}
+}
+
+// trackWidth advances the current line position the way a terminal would:
+// tabs stop every 8 columns and carriage returns and form feeds start over
+func (c *engine) trackWidth(r rune) {
+	switch r {
+	case '\n', '\r', '\f':
+		c.counts.checkLongest(c.linePos)
+		c.linePos = 0
+	case '\t':
+		c.linePos += 8 - c.linePos%8
+	default:
+		c.linePos += runeWidth(r)
+	}
+}
+
+// empty reports whether nothing was counted yet but lines
+func (c *engine) empty() bool {
+	return c.counts.Chars == 0 && c.counts.Invalid == 0
+}
+
+// result returns the counts so far. Every byte of a trailing incomplete rune
+// is counted as invalid, and extra adds that many invalid sequences, for a
+// decoder that ended in the middle of a sequence.
+func (c *engine) result(extra int) Result {
+	counts := c.counts
+	if c.lineWidths {
+		counts.checkLongest(c.linePos)
+	}
+	trailing := len(c.pending) + extra
+	if trailing > 0 {
+		counts.PartialLine = true
+	}
+	if trailing > 0 && !c.linesOnly {
+		counts.Invalid += trailing
+		if !c.inWord {
+			counts.Words++
+		}
+	}
+
+	// Segment counters are copied so the trailing invalid bytes do not
+	// change the state of the running counter
+	if c.graphemes != nil {
+		counts.Chars = c.graphemes.Count()
+	}
+	if c.words != nil {
+		words := *c.words
+		for range trailing {
+			words.Add(utf8.RuneError)
+		}
+		counts.Words = words.Count()
+	}
+	if c.code != nil {
+		code := c.code.clone()
+		for range trailing {
+			code.add(utf8.RuneError)
+		}
+		counts.Code, counts.Comments, counts.Blank = code.counts()
+	}
+	if c.matcher != nil {
+		counts.Matches = c.matcher.result()
+	}
+	if c.stats != nil {
+		counts.Stats = c.stats.result()
+	}
+	if c.prose != nil {
+		prose := c.prose.clone()
+		for range trailing {
+			prose.add(utf8.RuneError, false)
+		}
+		counts.Sentences, counts.Paragraphs, counts.Syllables = prose.counts()
+	}
+	if c.document != nil {
+		c.document.result(&counts)
+	}
+	return counts
+}
+
+func (c *engine) startsInWord() bool {
+	if !c.empty() {
+		return c.firstInWord
+	}
+	return len(c.pending) > 0
+}
+
+func (c *engine) endsInWord() bool {
+	return c.inWord || len(c.pending) > 0
+}
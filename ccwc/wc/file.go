@@ -0,0 +1,74 @@
+package wc
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"runtime/debug"
+)
+
+// MmapThreshold is the size from which CountFile maps a regular file into
+// memory instead of reading it through a buffer
+const MmapThreshold = 4 << 20
+
+// errTruncated is returned for a mapped file that was truncated while it was
+// counted, since its missing pages cannot be read
+var errTruncated = errors.New("file truncated while it was read")
+
+// CountFile counts f from its current offset to EOF. A regular file of at
+// least MmapThreshold bytes read from its start is mapped into memory where
+// the platform supports it, which saves copying it, unless Options.NoMmap is
+// set. It is read like any other reader otherwise, or when it cannot be mapped.
+func CountFile(f *os.File, options Options) (Result, error) {
+	if options.NoMmap {
+		return Count(f, options)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return Result{}, err
+	}
+	offset, err := f.Seek(0, io.SeekCurrent)
+	size := info.Size()
+	if err != nil || offset != 0 || !info.Mode().IsRegular() || size < MmapThreshold || int64(int(size)) != size {
+		return Count(f, options)
+	}
+
+	data, err := mapFile(f, int(size))
+	if err != nil {
+		return Count(f, options)
+	}
+	defer unmapFile(data)
+	return countMapped(data, options)
+}
+
+// countMapped counts a mapped file. Reading a page past the end of a file
+// that was truncated meanwhile faults, which is turned into errTruncated
+// instead of crashing the process.
+func countMapped(data []byte, options Options) (result Result, err error) {
+	defer debug.SetPanicOnFault(debug.SetPanicOnFault(true))
+	defer func() {
+		if r := recover(); r != nil {
+			// A fault is a runtime.Error with the address it faulted at
+			if _, ok := r.(interface{ Addr() uintptr }); ok {
+				result, err = Result{}, errTruncated
+				return
+			}
+			panic(r)
+		}
+	}()
+
+	if options.Decompress && IsCompressed(data) {
+		return countCompressed(bytes.NewReader(data), options)
+	}
+	// The mapping is fed in slices like a read buffer, which bounds what the
+	// decoder converts at once
+	c := NewCounter(options)
+	for p := data; len(p) > 0; {
+		n := min(len(p), bufferSize)
+		c.Write(p[:n])
+		p = p[n:]
+	}
+	c.finish()
+	return c.Result(), nil
+}
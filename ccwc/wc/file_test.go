@@ -0,0 +1,122 @@
+package wc
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// writeTempFile writes data to a new file and opens it
+func writeTempFile(tb testing.TB, data []byte) *os.File {
+	tb.Helper()
+	path := filepath.Join(tb.TempDir(), "input")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		tb.Fatal(err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(func() { f.Close() })
+	return f
+}
+
+func TestCountFileMmap(t *testing.T) {
+	// Past MmapThreshold, so the file is mapped where the platform can
+	inputs := map[string][]byte{
+		"ascii":     bytes.Repeat([]byte("The quick brown fox\tjumps over the lazy dog.\n"), MmapThreshold/45+1),
+		"multibyte": bytes.Repeat([]byte("Größenwahn und 日本語のテキスト, naïve café.\n"), MmapThreshold/50+1),
+		"utf-16":    append([]byte("\xff\xfe"), bytes.Repeat([]byte("h\x00i\x00 \x00\n\x00"), MmapThreshold/8+1)...),
+	}
+	for name, data := range inputs {
+		for _, metrics := range []Metric{MetricBytes | MetricLines | MetricWords, MetricAll} {
+			buffered, err := CountFile(writeTempFile(t, data), Options{Metrics: metrics, NoMmap: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+			mapped, err := CountFile(writeTempFile(t, data), Options{Metrics: metrics})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(mapped, buffered) {
+				t.Errorf("%s with metrics %b: mapped %+v, buffered %+v", name, metrics, mapped, buffered)
+			}
+		}
+	}
+}
+
+func TestCountMappedTruncated(t *testing.T) {
+	data := bytes.Repeat([]byte("x\n"), MmapThreshold)
+	f := writeTempFile(t, data)
+	mapped, err := mapFile(f, len(data))
+	if err != nil {
+		t.Skipf("cannot map files: %v", err)
+	}
+	defer unmapFile(mapped)
+
+	if err := os.Truncate(f.Name(), 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := countMapped(mapped, Options{Metrics: MetricLines}); !errors.Is(err, errTruncated) {
+		t.Errorf("err = %v, want %v", err, errTruncated)
+	}
+}
+
+func TestCounterWriteKeepsOnlyHead(t *testing.T) {
+	c := NewCounter(Options{Metrics: MetricLines})
+	c.Write(bytes.Repeat([]byte("x\n"), bufferSize))
+	if c.head != nil {
+		t.Errorf("head kept %d bytes after the encoding was detected", len(c.head))
+	}
+
+	c = NewCounter(Options{Metrics: MetricLines})
+	c.Write([]byte("x"))
+	c.Write(bytes.Repeat([]byte("x\n"), bufferSize))
+	if c.head != nil {
+		t.Errorf("head kept %d bytes after a short first write", len(c.head))
+	}
+	if got := c.Result().Lines; got != bufferSize {
+		t.Errorf("lines = %d, want %d", got, bufferSize)
+	}
+}
+
+// BenchmarkCountFile compares mapping a file with reading it through a
+// buffer, for the SWAR scanner and for the rune by rune path
+func BenchmarkCountFile(b *testing.B) {
+	modes := []struct {
+		name    string
+		options Options
+	}{
+		{"swar", Options{Metrics: MetricBytes | MetricLines | MetricWords}},
+		{"chars", Options{Metrics: MetricBytes | MetricLines | MetricWords | MetricChars | MetricMaxLineWidth}},
+	}
+	for _, input := range []string{"ascii", "multibyte"} {
+		// benchmarkInputs are below MmapThreshold, so they are repeated
+		data := bytes.Repeat(benchmarkInputs[input], 4)
+		f := writeTempFile(b, data)
+		for _, mode := range modes {
+			for _, mmap := range []bool{false, true} {
+				name := input + "/" + mode.name + "/buffered"
+				if mmap {
+					name = input + "/" + mode.name + "/mmap"
+				}
+				options := mode.options
+				options.NoMmap = !mmap
+				b.Run(name, func(b *testing.B) {
+					b.SetBytes(int64(len(data)))
+					for i := 0; i < b.N; i++ {
+						if _, err := f.Seek(0, 0); err != nil {
+							b.Fatal(err)
+						}
+						if _, err := CountFile(f, options); err != nil {
+							b.Fatal(err)
+						}
+					}
+				})
+			}
+		}
+	}
+}
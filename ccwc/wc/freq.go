@@ -0,0 +1,220 @@
+package wc
+
+import (
+	"container/heap"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// DefaultCapacity is how many distinct values a Frequencies table tracks by
+// default. Counts are exact as long as there are no more distinct values.
+const DefaultCapacity = 1 << 16
+
+// wordBatch is how many words an input collects before adding them to the
+// shared table, so the lock is not taken for every word
+const wordBatch = 1024
+
+// Entry is a value with the number of times it occurred. Once more distinct
+// values than the capacity of the table were seen, Count may overestimate by
+// up to Error.
+type Entry struct {
+	Value string
+	Count int
+	Error int
+}
+
+// Frequencies finds the most frequent words and chars of the inputs counted
+// with it, see Options.Frequencies. Words are split on whitespace like the
+// word count. It uses the Space-Saving algorithm, so its memory is bounded by
+// its capacity however large the inputs are. It is safe for concurrent use.
+type Frequencies struct {
+	// Words and Chars select what is counted
+	Words bool
+	Chars bool
+	// FoldCase counts words and chars regardless of their case
+	FoldCase bool
+	// StripPunct removes the punctuation around words, and ignores
+	// punctuation chars
+	StripPunct bool
+
+	mu    sync.Mutex
+	words *topK
+	chars *topK
+}
+
+// NewFrequencies returns a table that tracks up to capacity distinct words
+// and as many distinct chars
+func NewFrequencies(capacity int) *Frequencies {
+	if capacity < 1 {
+		capacity = DefaultCapacity
+	}
+	return &Frequencies{words: newTopK(capacity), chars: newTopK(capacity)}
+}
+
+// TopWords returns the n most frequent words, most frequent first
+func (f *Frequencies) TopWords(n int) []Entry {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.words.top(n)
+}
+
+// TopChars returns the n most frequent chars, most frequent first
+func (f *Frequencies) TopChars(n int) []Entry {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.chars.top(n)
+}
+
+func (f *Frequencies) normalizeWord(word string) string {
+	if f.StripPunct {
+		word = strings.TrimFunc(word, unicode.IsPunct)
+	}
+	if f.FoldCase {
+		word = strings.ToLower(word)
+	}
+	return word
+}
+
+// add merges the words and chars collected from one input
+func (f *Frequencies) add(words []string, chars map[rune]int) {
+	for i, w := range words {
+		words[i] = f.normalizeWord(w)
+	}
+	merged := make(map[rune]int, len(chars))
+	for r, n := range chars {
+		if f.StripPunct && unicode.IsPunct(r) {
+			continue
+		}
+		if f.FoldCase {
+			r = unicode.ToLower(r)
+		}
+		merged[r] += n
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, w := range words {
+		if w != "" {
+			f.words.add(w, 1)
+		}
+	}
+	for r, n := range merged {
+		f.chars.add(string(r), n)
+	}
+}
+
+// frequencyTracker collects the words and chars of one input for a
+// Frequencies table
+type frequencyTracker struct {
+	table *Frequencies
+	word  []byte // the word being read
+	words []string
+	chars map[rune]int
+}
+
+func newFrequencyTracker(table *Frequencies) *frequencyTracker {
+	return &frequencyTracker{table: table, chars: map[rune]int{}}
+}
+
+func (t *frequencyTracker) add(r rune, space bool) {
+	if space {
+		t.endWord()
+		return
+	}
+	if t.table.Chars {
+		t.chars[r]++
+	}
+	if t.table.Words {
+		t.word = utf8.AppendRune(t.word, r)
+	}
+}
+
+func (t *frequencyTracker) endWord() {
+	if len(t.word) == 0 {
+		return
+	}
+	t.words = append(t.words, string(t.word))
+	t.word = t.word[:0]
+	if len(t.words) >= wordBatch {
+		t.table.add(t.words, nil)
+		t.words = t.words[:0]
+	}
+}
+
+// flush adds what was collected to the table, including the last word
+func (t *frequencyTracker) flush() {
+	t.endWord()
+	t.table.add(t.words, t.chars)
+	t.words = t.words[:0]
+	t.chars = map[rune]int{}
+}
+
+// topK keeps the counts of up to capacity values in a min-heap. A new value
+// seen when it is full replaces the least frequent one and inherits its
+// count, which bounds its overestimation.
+type topK struct {
+	capacity int
+	entries  []Entry
+	index    map[string]int // position of every value in entries
+}
+
+func newTopK(capacity int) *topK {
+	return &topK{capacity: capacity, index: map[string]int{}}
+}
+
+func (t *topK) Len() int           { return len(t.entries) }
+func (t *topK) Less(i, j int) bool { return t.entries[i].Count < t.entries[j].Count }
+func (t *topK) Swap(i, j int) {
+	t.entries[i], t.entries[j] = t.entries[j], t.entries[i]
+	t.index[t.entries[i].Value] = i
+	t.index[t.entries[j].Value] = j
+}
+
+func (t *topK) Push(x any) {
+	e := x.(Entry)
+	t.index[e.Value] = len(t.entries)
+	t.entries = append(t.entries, e)
+}
+
+func (t *topK) Pop() any {
+	e := t.entries[len(t.entries)-1]
+	t.entries = t.entries[:len(t.entries)-1]
+	delete(t.index, e.Value)
+	return e
+}
+
+func (t *topK) add(value string, n int) {
+	if i, ok := t.index[value]; ok {
+		t.entries[i].Count += n
+		heap.Fix(t, i)
+		return
+	}
+	if len(t.entries) < t.capacity {
+		heap.Push(t, Entry{Value: value, Count: n})
+		return
+	}
+
+	least := t.entries[0]
+	delete(t.index, least.Value)
+	t.entries[0] = Entry{Value: value, Count: least.Count + n, Error: least.Count}
+	t.index[value] = 0
+	heap.Fix(t, 0)
+}
+
+// top returns the n largest counts, ties broken by value
+func (t *topK) top(n int) []Entry {
+	entries := append([]Entry(nil), t.entries...)
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Count != entries[j].Count {
+			return entries[i].Count > entries[j].Count
+		}
+		return entries[i].Value < entries[j].Value
+	})
+	if n < len(entries) {
+		entries = entries[:n]
+	}
+	return entries
+}
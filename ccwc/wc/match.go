@@ -0,0 +1,55 @@
+package wc
+
+import (
+	"regexp"
+	"unicode/utf8"
+)
+
+// Match counts the matches of one pattern
+type Match struct {
+	// Count is the number of non-overlapping matches
+	Count int
+	// Lines is the number of lines with at least one match
+	Lines int
+}
+
+// lineMatcher matches patterns against every line, so matches never span
+// a newline
+type lineMatcher struct {
+	patterns []*regexp.Regexp
+	matches  []Match
+	line     []byte // the current line, without its newline
+}
+
+func newLineMatcher(patterns []*regexp.Regexp) *lineMatcher {
+	return &lineMatcher{patterns: patterns, matches: make([]Match, len(patterns))}
+}
+
+func (m *lineMatcher) add(r rune) {
+	if r == '\n' {
+		m.match(m.matches, m.line)
+		m.line = m.line[:0]
+		return
+	}
+	m.line = utf8.AppendRune(m.line, r)
+}
+
+// match adds the matches of every pattern in line to matches
+func (m *lineMatcher) match(matches []Match, line []byte) {
+	for i, re := range m.patterns {
+		if n := len(re.FindAllIndex(line, -1)); n > 0 {
+			matches[i].Count += n
+			matches[i].Lines++
+		}
+	}
+}
+
+// result returns the matches so far, including those of an unterminated last
+// line, without changing the state of m
+func (m *lineMatcher) result() []Match {
+	matches := append([]Match(nil), m.matches...)
+	if len(m.line) > 0 {
+		m.match(matches, m.line)
+	}
+	return matches
+}
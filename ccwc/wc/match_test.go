@@ -0,0 +1,69 @@
+package wc
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestMatches(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		patterns []string
+		want     []Match
+	}{
+		{
+			name:     "no input",
+			patterns: []string{"a"},
+			want:     []Match{{}},
+		},
+		{
+			name:     "matches and matching lines",
+			input:    "ERROR a ERROR\nok\nERROR\n",
+			patterns: []string{"ERROR"},
+			want:     []Match{{Count: 3, Lines: 2}},
+		},
+		{
+			name:     "several patterns",
+			input:    "foo bar\nbaz foo foo\n",
+			patterns: []string{"foo", "ba.", "nothing"},
+			want:     []Match{{Count: 3, Lines: 2}, {Count: 2, Lines: 2}, {}},
+		},
+		{
+			name:     "unterminated last line",
+			input:    "x\nlast x x",
+			patterns: []string{"x"},
+			want:     []Match{{Count: 3, Lines: 2}},
+		},
+		{
+			name:     "matches do not span lines",
+			input:    "a\nb\n",
+			patterns: []string{`a\s*b`, "^b$"},
+			want:     []Match{{}, {Count: 1, Lines: 1}},
+		},
+		{
+			name:     "multibyte",
+			input:    "日本 日本\n",
+			patterns: []string{"日本"},
+			want:     []Match{{Count: 2, Lines: 1}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var patterns []*regexp.Regexp
+			for _, p := range tt.patterns {
+				patterns = append(patterns, regexp.MustCompile(p))
+			}
+			options := Options{Metrics: MetricMatches, Patterns: patterns}
+			got, err := Count(strings.NewReader(tt.input), options)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got.Matches, tt.want) {
+				t.Errorf("matches = %+v, want %+v", got.Matches, tt.want)
+			}
+		})
+	}
+}
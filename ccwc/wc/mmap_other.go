@@ -0,0 +1,17 @@
+//go:build !linux
+
+package wc
+
+import (
+	"errors"
+	"os"
+)
+
+// mapFile is only implemented on Linux, elsewhere files are always read
+func mapFile(f *os.File, size int) ([]byte, error) {
+	return nil, errors.ErrUnsupported
+}
+
+func unmapFile(data []byte) error {
+	return nil
+}
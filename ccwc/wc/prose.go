@@ -0,0 +1,250 @@
+package wc
+
+import (
+	"strings"
+	"unicode"
+)
+
+// abbreviations are the words that are usually followed by a period that
+// does not end the sentence, in lower case and without their last period
+var abbreviations = map[string]bool{
+	"mr": true, "mrs": true, "ms": true, "dr": true, "prof": true, "st": true,
+	"jr": true, "sr": true, "vs": true, "e.g": true, "i.e": true, "cf": true,
+	"approx": true, "inc": true, "ltd": true, "corp": true, "dept": true, "vol": true,
+	"feb": true, "apr": true, "jul": true, "aug": true, "sep": true, "sept": true,
+	"oct": true, "nov": true,
+}
+
+// ambiguousAbbreviations are also common words, or often end a sentence, so
+// their period only continues the sentence when the next word starts with a
+// lower case letter or a digit, as in "No. 5" or "Dec. 24"
+var ambiguousAbbreviations = map[string]bool{
+	"no": true, "co": true, "est": true, "etc": true, "fig": true,
+	"jan": true, "mar": true, "jun": true, "dec": true,
+}
+
+// Kinds of words ending with a period, see proseTracker.abbreviation
+const (
+	notAbbreviation = iota
+	isAbbreviation
+	maybeAbbreviation // depending on the next word
+)
+
+// longestAbbreviation bounds how much of a word is kept to look it up
+const longestAbbreviation = 8
+
+const (
+	terminals = ".!?…"
+	// closers may follow the punctuation that ends a sentence, as in "Stop!"
+	closers = "\"')]}»”’"
+	vowels  = "aeiouy"
+)
+
+// Readability returns the Flesch reading ease of the input, which is higher
+// for text that is easier to read and mostly between 0 and 100. It is 0 when
+// the input has no words.
+func (r Result) Readability() float64 {
+	if r.Words == 0 || r.Sentences == 0 {
+		return 0
+	}
+	return 206.835 - 1.015*r.SentenceLength() - 84.6*float64(r.Syllables)/float64(r.Words)
+}
+
+// SentenceLength returns the mean number of words of a sentence
+func (r Result) SentenceLength() float64 {
+	if r.Sentences == 0 {
+		return 0
+	}
+	return float64(r.Words) / float64(r.Sentences)
+}
+
+// proseTracker counts the sentences, paragraphs and syllables of prose.
+//
+// A sentence ends with a word that ends with '.', '!', '?' or '…', possibly
+// followed by closing quotes or brackets, unless the word is an abbreviation
+// like "Dr." or an initial like "J.". After a word that is also a common
+// word, like "no.", the next word decides. A paragraph is a block of lines
+// that are not blank, and its end also ends a sentence without punctuation,
+// like a heading.
+type proseTracker struct {
+	sentences, paragraphs, syllables int
+
+	inSentence  bool // a word of a sentence that did not end yet was seen
+	pending     bool // the last word may end the sentence, see abbreviation
+	inParagraph bool
+	lineBlank   bool // the current line is only whitespace so far
+
+	// The current word
+	inWord    bool
+	upper     bool   // its first letter is upper case
+	head      []rune // the first runes, in lower case
+	length    int    // in runes, without the closers at its end
+	terminal  bool   // ends with sentence punctuation
+	dots      int    // number of periods at its end
+	hasText   bool   // has letters or digits
+	hasLetter bool
+	groups    int  // vowel groups, which estimate the syllables
+	vowel     bool // the last rune is a vowel
+	last      [2]rune
+}
+
+func newProseTracker() *proseTracker {
+	return &proseTracker{lineBlank: true}
+}
+
+func (t *proseTracker) add(r rune, space bool) {
+	if space {
+		if t.inWord {
+			t.endWord()
+		}
+		if r == '\n' {
+			if t.lineBlank && t.inParagraph {
+				t.endParagraph()
+			}
+			t.lineBlank = true
+		}
+		return
+	}
+
+	if !t.inParagraph {
+		t.paragraphs++
+		t.inParagraph = true
+	}
+	t.lineBlank = false
+	if !t.inWord {
+		t.startWord(r)
+	}
+	if !t.hasLetter && unicode.IsLetter(r) {
+		t.upper = unicode.IsUpper(r)
+	}
+	t.addRune(unicode.ToLower(r))
+}
+
+// startWord starts a word with r, which ends the sentence before it when the
+// word before ended with an ambiguous abbreviation and r does not continue it
+func (t *proseTracker) startWord(r rune) {
+	if t.pending {
+		t.pending = false
+		if !unicode.IsLower(r) && !unicode.IsDigit(r) {
+			t.sentences++
+			t.inSentence = false
+		}
+	}
+	t.inWord = true
+}
+
+func (t *proseTracker) addRune(r rune) {
+	if strings.ContainsRune(closers, r) && t.terminal {
+		return
+	}
+	if len(t.head) < longestAbbreviation+1 {
+		t.head = append(t.head, r)
+	}
+	t.length++
+	t.terminal = strings.ContainsRune(terminals, r)
+	if r == '.' {
+		t.dots++
+	} else {
+		t.dots = 0
+	}
+
+	if unicode.IsLetter(r) || unicode.IsDigit(r) {
+		t.hasText = true
+	}
+	if !unicode.IsLetter(r) {
+		t.vowel = false
+		return
+	}
+	t.hasLetter = true
+	vowel := strings.ContainsRune(vowels, r)
+	if vowel && !t.vowel {
+		t.groups++
+	}
+	t.vowel = vowel
+	t.last = [2]rune{t.last[1], r}
+}
+
+func (t *proseTracker) endWord() {
+	t.syllables += t.wordSyllables()
+	if t.hasText {
+		t.inSentence = true
+	}
+	if t.inSentence && t.terminal {
+		switch t.abbreviation() {
+		case notAbbreviation:
+			t.sentences++
+			t.inSentence = false
+		case maybeAbbreviation:
+			t.pending = true
+		}
+	}
+
+	t.inWord, t.upper, t.head, t.length, t.terminal, t.dots = false, false, t.head[:0], 0, false, 0
+	t.hasText, t.hasLetter, t.groups, t.vowel, t.last = false, false, 0, false, [2]rune{}
+}
+
+// wordSyllables estimates the syllables of the current word as its groups of
+// vowels, without a silent e at its end. Words without letters, like
+// numbers, count as one syllable.
+func (t *proseTracker) wordSyllables() int {
+	if !t.hasLetter {
+		if t.hasText {
+			return 1
+		}
+		return 0
+	}
+	n := t.groups
+	if t.last[1] == 'e' && t.last[0] != 'l' && n > 1 {
+		n--
+	}
+	return max(n, 1)
+}
+
+// abbreviation tells whether the current word is an abbreviation or an
+// initial, whose period does not end a sentence. A lower case letter may be
+// an abbreviation like "p." as well as the end of a sentence.
+func (t *proseTracker) abbreviation() int {
+	if t.dots != 1 || t.length > longestAbbreviation+1 {
+		return notAbbreviation
+	}
+	word := strings.TrimLeft(string(t.head[:t.length-1]), closers+"(")
+	if len([]rune(word)) == 1 && unicode.IsLetter([]rune(word)[0]) {
+		if t.upper {
+			return isAbbreviation
+		}
+		return maybeAbbreviation
+	}
+	switch {
+	case abbreviations[word]:
+		return isAbbreviation
+	case ambiguousAbbreviations[word]:
+		return maybeAbbreviation
+	}
+	return notAbbreviation
+}
+
+func (t *proseTracker) endParagraph() {
+	t.pending = false
+	if t.inSentence {
+		t.sentences++
+		t.inSentence = false
+	}
+	t.inParagraph = false
+}
+
+// counts returns the sentences, paragraphs and syllables so far, including
+// an unterminated last word and sentence, without changing the state of t
+func (t *proseTracker) counts() (sentences, paragraphs, syllables int) {
+	end := t.clone()
+	if end.inWord {
+		end.endWord()
+	}
+	end.endParagraph()
+	return end.sentences, end.paragraphs, end.syllables
+}
+
+func (t *proseTracker) clone() *proseTracker {
+	clone := *t
+	clone.head = append([]rune(nil), t.head...)
+	return &clone
+}
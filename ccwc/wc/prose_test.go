@@ -0,0 +1,107 @@
+package wc
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestProse(t *testing.T) {
+	tests := []struct {
+		name       string
+		input      string
+		sentences  int
+		paragraphs int
+		syllables  int
+	}{
+		{
+			name: "empty",
+		},
+		{
+			name:       "common words that are abbreviations",
+			input:      "He said no. She left. It was Dec. Then more.\n",
+			sentences:  4,
+			paragraphs: 1,
+			syllables:  10,
+		},
+		{
+			name:       "abbreviations before numbers",
+			input:      "See No. 5 and Dec. 24 on p. 3. Done.\n",
+			sentences:  2,
+			paragraphs: 1,
+			syllables:  10,
+		},
+		{
+			name:       "titles and initials",
+			input:      "Dr. Smith met J. R. Tolkien. They talked.\n",
+			sentences:  2,
+			paragraphs: 1,
+			syllables:  10,
+		},
+		{
+			name:       "etc",
+			input:      "Mr. and Mrs. Jones ate etc. and left. We bought pears, etc. Then we went.\n",
+			sentences:  3,
+			paragraphs: 1,
+			syllables:  16,
+		},
+		{
+			name:       "closing quotes and brackets",
+			input:      "\"Stop!\" she said. (He did.) Then nothing.\n",
+			sentences:  4,
+			paragraphs: 1,
+			syllables:  8,
+		},
+		{
+			name:       "headings without punctuation",
+			input:      "Title\n\nFirst paragraph here. Second one\n\nLast\n",
+			sentences:  4,
+			paragraphs: 3,
+			syllables:  11,
+		},
+		{
+			name:       "blank lines with whitespace",
+			input:      "a.\n \t\nb.\n\n\nc.",
+			sentences:  3,
+			paragraphs: 3,
+			syllables:  3,
+		},
+		{
+			name:       "syllables",
+			input:      "the cat ate apples table 42\n",
+			sentences:  1,
+			paragraphs: 1,
+			syllables:  8,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Count(strings.NewReader(tt.input), Options{Metrics: MetricProse})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Sentences != tt.sentences || got.Paragraphs != tt.paragraphs || got.Syllables != tt.syllables {
+				t.Errorf("sentences, paragraphs, syllables = %d, %d, %d, want %d, %d, %d",
+					got.Sentences, got.Paragraphs, got.Syllables, tt.sentences, tt.paragraphs, tt.syllables)
+			}
+		})
+	}
+}
+
+func TestReadability(t *testing.T) {
+	tests := []struct {
+		result Result
+		want   float64
+	}{
+		{Result{}, 0},
+		{Result{Words: 3}, 0},
+		{Result{Words: 10, Sentences: 2, Syllables: 15}, 74.86},
+		{Result{Words: 4, Sentences: 4, Syllables: 4}, 121.22},
+	}
+	for _, tt := range tests {
+		if got := tt.result.Readability(); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("Readability of %d words, %d sentences, %d syllables = %v, want %v",
+				tt.result.Words, tt.result.Sentences, tt.result.Syllables, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package wc
+
+import (
+	"sort"
+)
+
+// LineStats describes the distribution of the lines of an input. Lengths are
+// in chars, without the newline. An unterminated last line is a line too.
+type LineStats struct {
+	Lines     int
+	MinLength int
+	MaxLength int
+	// Lengths and WordsPerLine map a line length or a number of words to the
+	// number of lines that have it. Keeping the distribution instead of every
+	// line bounds the memory and still gives an exact median.
+	Lengths      map[int]int
+	WordsPerLine map[int]int
+	totalLength  int
+}
+
+func newLineStats() *LineStats {
+	return &LineStats{Lengths: map[int]int{}, WordsPerLine: map[int]int{}}
+}
+
+func (s *LineStats) addLine(length, words int) {
+	if s.Lines == 0 || length < s.MinLength {
+		s.MinLength = length
+	}
+	if length > s.MaxLength {
+		s.MaxLength = length
+	}
+	s.Lines++
+	s.totalLength += length
+	s.Lengths[length]++
+	s.WordsPerLine[words]++
+}
+
+// Mean returns the mean line length
+func (s *LineStats) Mean() float64 {
+	if s.Lines == 0 {
+		return 0
+	}
+	return float64(s.totalLength) / float64(s.Lines)
+}
+
+// Median returns the median line length, the mean of the two middle lengths
+// for an even number of lines
+func (s *LineStats) Median() float64 {
+	if s.Lines == 0 {
+		return 0
+	}
+	lengths := make([]int, 0, len(s.Lengths))
+	for length := range s.Lengths {
+		lengths = append(lengths, length)
+	}
+	sort.Ints(lengths)
+
+	// nth returns the length of the nth line, in increasing length order
+	nth := func(n int) int {
+		for _, length := range lengths {
+			if n < s.Lengths[length] {
+				return length
+			}
+			n -= s.Lengths[length]
+		}
+		return 0
+	}
+	if s.Lines%2 == 1 {
+		return float64(nth(s.Lines / 2))
+	}
+	return float64(nth(s.Lines/2-1)+nth(s.Lines/2)) / 2
+}
+
+// Add accumulates the lines of other into s
+func (s *LineStats) Add(other *LineStats) {
+	if other == nil || other.Lines == 0 {
+		return
+	}
+	if s.Lines == 0 || other.MinLength < s.MinLength {
+		s.MinLength = other.MinLength
+	}
+	if other.MaxLength > s.MaxLength {
+		s.MaxLength = other.MaxLength
+	}
+	s.Lines += other.Lines
+	s.totalLength += other.totalLength
+	for length, n := range other.Lengths {
+		s.Lengths[length] += n
+	}
+	for words, n := range other.WordsPerLine {
+		s.WordsPerLine[words] += n
+	}
+}
+
+func (s *LineStats) clone() *LineStats {
+	clone := newLineStats()
+	clone.Add(s)
+	return clone
+}
+
+// lineStatsTracker measures the current line for LineStats
+type lineStatsTracker struct {
+	stats  *LineStats
+	length int
+	words  int
+	inWord bool
+	inLine bool // the current line is not empty
+}
+
+func newLineStatsTracker() *lineStatsTracker {
+	return &lineStatsTracker{stats: newLineStats()}
+}
+
+func (t *lineStatsTracker) add(r rune, space bool) {
+	if r == '\n' {
+		t.stats.addLine(t.length, t.words)
+		t.length, t.words, t.inWord, t.inLine = 0, 0, false, false
+		return
+	}
+	t.length++
+	t.inLine = true
+	if space {
+		t.inWord = false
+	} else if !t.inWord {
+		t.words++
+		t.inWord = true
+	}
+}
+
+// result returns the stats so far, including an unterminated last line,
+// without changing the state of t
+func (t *lineStatsTracker) result() *LineStats {
+	stats := t.stats.clone()
+	if t.inLine {
+		stats.addLine(t.length, t.words)
+	}
+	return stats
+}
@@ -0,0 +1,58 @@
+package wc
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+// The scanner below reads 8 bytes at a time as one uint64 and classifies all
+// of them at once (SWAR, SIMD within a register). It only handles ASCII, so a
+// word with a byte of 0x80 or more is left to the rune by rune path.
+const (
+	ones  = 0x0101010101010101
+	highs = 0x8080808080808080
+)
+
+// equalMask returns the high bit of every byte of x equal to b. Every byte of
+// x must be ASCII, so adding 0x7f to a byte never carries into the next one.
+func equalMask(x uint64, b byte) uint64 {
+	t := x ^ (ones * uint64(b))
+	return ^(t + ones*0x7f) & highs
+}
+
+// spaceMask returns the high bit of every byte of x that unicode.IsSpace
+// reports as space: '\t' to '\r' and ' '. Every byte of x must be ASCII.
+func spaceMask(x uint64) uint64 {
+	// The high bit of x+0x80-n is set for the bytes from n on
+	controls := (x + ones*(0x80-'\t')) &^ (x + ones*(0x80-'\r'-1))
+	return (controls | equalMask(x, ' ')) & highs
+}
+
+// scanASCII counts the leading 8 byte words of p that are all ASCII, and
+// returns how many bytes it counted
+func (c *engine) scanASCII(p []byte) int {
+	n := 0
+	for ; n+8 <= len(p); n += 8 {
+		x := binary.LittleEndian.Uint64(p[n:])
+		if x&highs != 0 {
+			break
+		}
+		spaces := spaceMask(x)
+		nonSpaces := ^spaces & highs
+		if c.empty() {
+			c.firstInWord = nonSpaces&0x80 != 0
+		}
+
+		// A word starts at a byte that is not a space and follows a space,
+		// which is the last byte of the previous word for the first byte
+		before := spaces << 8
+		if !c.inWord {
+			before |= 0x80
+		}
+		c.counts.Words += bits.OnesCount64(nonSpaces & before)
+		c.counts.Lines += bits.OnesCount64(equalMask(x, '\n'))
+		c.counts.Chars += 8
+		c.inWord = nonSpaces>>63 != 0
+	}
+	return n
+}
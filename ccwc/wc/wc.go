@@ -0,0 +1,321 @@
+// Package wc counts the bytes, lines, words and characters of a text in a
+// single streaming pass, the way wc(1) does.
+//
+// Count reads a whole io.Reader. A Counter is an io.Writer for inputs that
+// arrive in pieces, and its Result can be taken at any point.
+package wc
+
+import (
+	"bytes"
+	"io"
+	"regexp"
+)
+
+const bufferSize = 64 * 1024
+
+// Metric is a set of counts to compute
+type Metric uint
+
+const (
+	// MetricBytes counts the bytes of the input, before it is decoded
+	MetricBytes Metric = 1 << iota
+	// MetricLines counts the newlines
+	MetricLines
+	// MetricWords counts the runs of chars between whitespace
+	MetricWords
+	// MetricChars counts the decoded chars
+	MetricChars
+	// MetricMaxLineWidth measures the display width of the longest line
+	MetricMaxLineWidth
+	// MetricLongestLine finds the number of the longest line
+	MetricLongestLine
+	// MetricInvalid counts the sequences that cannot be decoded
+	MetricInvalid
+	// MetricCode classifies lines as code, comment or blank
+	MetricCode
+	// MetricMatches matches Options.Patterns
+	MetricMatches
+	// MetricStats computes the LineStats
+	MetricStats
+	// MetricProse counts sentences, paragraphs and syllables
+	MetricProse
+	// MetricDocument counts the structure of Options.Document
+	MetricDocument
+
+	// MetricAll computes every metric
+	MetricAll = MetricBytes | MetricLines | MetricWords | MetricChars |
+		MetricMaxLineWidth | MetricLongestLine | MetricInvalid | MetricCode | MetricMatches | MetricStats |
+		MetricProse | MetricDocument
+)
+
+// Options selects which metrics are computed and how the input is decoded
+// and segmented. The zero value computes every metric of UTF-8 text, or of
+// UTF-16 text starting with a byte order mark.
+type Options struct {
+	// Metrics to compute. Zero means MetricAll. Metrics that were not asked
+	// for may be left zero in the Result.
+	Metrics Metric
+	// Encoding of the input, see ParseEncoding. Empty means EncodingAuto.
+	Encoding string
+	// Graphemes counts extended grapheme clusters as chars
+	Graphemes bool
+	// UnicodeWords counts words with the UAX #29 word boundaries instead of
+	// splitting on whitespace
+	UnicodeWords bool
+	// CountFinalPartialLine counts a last line without a trailing newline
+	CountFinalPartialLine bool
+	// Language tells comments from code for MetricCode, see LanguageOf. Nil
+	// counts every line that is not blank as code.
+	Language *Language
+	// Document is the structured format counted for MetricDocument, see
+	// DocumentOf. Empty counts nothing.
+	Document string
+	// Decompress counts the content of gzip, bzip2 and zlib inputs, which are
+	// recognized by their first bytes. A Counter never decompresses.
+	Decompress bool
+	// NoMmap makes CountFile read large regular files through a buffer
+	// instead of mapping them into memory
+	NoMmap bool
+	// Patterns are matched against every line for MetricMatches
+	Patterns []*regexp.Regexp
+	// Frequencies, when set, collects the words and chars of the input. A
+	// Counter only adds them once Count or CountAt is done with it.
+	Frequencies *Frequencies
+}
+
+func (o Options) wants(m Metric) bool {
+	if o.Metrics == 0 {
+		return true
+	}
+	return o.Metrics&m != 0
+}
+
+// Splittable reports whether an input counted with o can be split into
+// chunks whose results are merged. Line widths, segments, comments and
+// frequent words, sentences, paragraphs and documents depend on what precedes a chunk, so they cannot.
+func (o Options) Splittable() bool {
+	return !o.Graphemes && !o.UnicodeWords && o.Frequencies == nil &&
+		!o.wants(MetricMaxLineWidth|MetricLongestLine|MetricCode|MetricStats|MetricProse|MetricDocument) &&
+		(len(o.Patterns) == 0 || !o.wants(MetricMatches))
+}
+
+// finishLines applies the line counting mode. Like POSIX wc, lines are the
+// newline characters of the input, so an unterminated last line is not
+// counted unless CountFinalPartialLine is set.
+func (o Options) finishLines(result Result) Result {
+	if o.CountFinalPartialLine && result.PartialLine {
+		result.Lines++
+	}
+	return result
+}
+
+// Result holds the counts of one input
+type Result struct {
+	Bytes int
+	// CompressedBytes is the size of the input before it was decompressed,
+	// which is Bytes for an input that was not compressed
+	CompressedBytes int
+	Lines           int
+	Words           int
+	Chars           int
+	// MaxLineWidth is the display width of the longest line and LongestLine
+	// its 1-based number
+	MaxLineWidth int
+	LongestLine  int
+	// Invalid counts the sequences that could not be decoded
+	Invalid int
+	// Code, Comments and Blank split the lines of source code. An
+	// unterminated last line is always counted.
+	Code     int
+	Comments int
+	Blank    int
+	// Sentences, Paragraphs and Syllables describe prose, see Readability
+	Sentences  int
+	Paragraphs int
+	Syllables  int
+	// Rows and Columns are set for CSV and TSV documents, Records and Keys
+	// for JSON, and Headings, CodeBlocks and Links for Markdown. Columns is
+	// the number of fields of the widest row.
+	Rows       int
+	Columns    int
+	Records    int
+	Keys       int
+	Headings   int
+	CodeBlocks int
+	Links      int
+	// Matches has the matches of every pattern of Options.Patterns, in order
+	Matches []Match
+	// Stats is set for MetricStats
+	Stats *LineStats
+	// PartialLine reports whether the input ends with bytes after the last newline
+	PartialLine bool
+}
+
+// Add accumulates other into r, as for the total of several inputs. The
+// longest line is the longest of both.
+func (r *Result) Add(other Result) {
+	r.Bytes += other.Bytes
+	r.CompressedBytes += other.CompressedBytes
+	r.Lines += other.Lines
+	r.Words += other.Words
+	r.Chars += other.Chars
+	r.Invalid += other.Invalid
+	r.Code += other.Code
+	r.Comments += other.Comments
+	r.Blank += other.Blank
+	r.Sentences += other.Sentences
+	r.Paragraphs += other.Paragraphs
+	r.Syllables += other.Syllables
+	r.Rows += other.Rows
+	r.Columns = max(r.Columns, other.Columns)
+	r.Records += other.Records
+	r.Keys += other.Keys
+	r.Headings += other.Headings
+	r.CodeBlocks += other.CodeBlocks
+	r.Links += other.Links
+	for i, m := range other.Matches {
+		if i == len(r.Matches) {
+			r.Matches = append(r.Matches, Match{})
+		}
+		r.Matches[i].Count += m.Count
+		r.Matches[i].Lines += m.Lines
+	}
+	if other.Stats != nil {
+		if r.Stats == nil {
+			r.Stats = newLineStats()
+		}
+		r.Stats.Add(other.Stats)
+	}
+	if other.MaxLineWidth > r.MaxLineWidth {
+		r.MaxLineWidth = other.MaxLineWidth
+		r.LongestLine = other.LongestLine
+	}
+}
+
+func (r *Result) checkLongest(width int) {
+	if width > r.MaxLineWidth {
+		r.MaxLineWidth = width
+		r.LongestLine = r.Lines + 1
+	}
+}
+
+// Counter counts the bytes written to it. The encoding is detected from the
+// first bytes when Options.Encoding is EncodingAuto.
+type Counter struct {
+	options Options
+	engine  *engine
+	dec     decoder
+	out     []byte // decoded input handed to the engine
+	head    []byte // first bytes, kept until the encoding is known
+	decided bool
+	raw     int
+}
+
+// NewCounter returns a Counter that computes the metrics of options
+func NewCounter(options Options) *Counter {
+	return &Counter{options: options, engine: newEngine(options)}
+}
+
+// A UTF-16 byte order mark is two bytes, and inputs without one are UTF-8,
+// so two bytes are enough to detect the encoding
+const detectSize = 2
+
+// Write counts p as the continuation of what was written before. It never
+// fails.
+func (c *Counter) Write(p []byte) (int, error) {
+	n := len(p)
+	c.raw += n
+	if !c.decided {
+		// Only the bytes the encoding is detected from are kept, so a large
+		// first write is not copied
+		k := min(len(p), detectSize-len(c.head))
+		c.head = append(c.head, p[:k]...)
+		p = p[k:]
+		if len(c.head) < detectSize {
+			return n, nil
+		}
+		c.decide()
+		head := c.head
+		c.head = nil
+		c.feed(head)
+	}
+	if len(p) > 0 {
+		c.feed(p)
+	}
+	return n, nil
+}
+
+// feed decodes p and hands it to the engine
+func (c *Counter) feed(p []byte) {
+	if c.dec == nil {
+		c.engine.Write(p)
+		return
+	}
+	c.out = c.dec.decode(c.out[:0], p)
+	c.engine.Write(c.out)
+}
+
+// finish ends the input, which adds its words and chars to
+// Options.Frequencies
+func (c *Counter) finish() {
+	if !c.decided {
+		c.decide()
+		p := c.head
+		c.head = nil
+		c.feed(p)
+	}
+	if c.engine.freq != nil {
+		c.engine.freq.flush()
+	}
+}
+
+// decide picks the decoder from the first bytes. A UTF-16 byte order mark
+// only tells the byte order, so it is dropped instead of counted as a char.
+func (c *Counter) decide() {
+	c.decided = true
+	encoding := detectEncoding(c.head, c.options.Encoding)
+	c.dec = newDecoder(encoding)
+	if bom := byteOrderMark(encoding); bom != nil && bytes.HasPrefix(c.head, bom) {
+		c.head = c.head[len(bom):]
+	}
+}
+
+// Result returns the counts of the input written so far. An incomplete
+// sequence at the end is counted as invalid, but is still completed by a
+// later Write.
+func (c *Counter) Result() Result {
+	if !c.decided {
+		// Too short to detect anything, so count it on its own
+		pending := NewCounter(c.options)
+		pending.decided = true
+		pending.dec = newDecoder(detectEncoding(c.head, c.options.Encoding))
+		pending.Write(c.head)
+		return pending.Result()
+	}
+
+	var result Result
+	if c.dec == nil {
+		result = c.engine.result(0)
+	} else {
+		result = c.engine.result(c.dec.trailing())
+	}
+	result.Bytes = c.raw
+	result.CompressedBytes = c.raw
+	return c.options.finishLines(result)
+}
+
+// Count reads r until EOF and returns its counts
+func Count(r io.Reader, options Options) (Result, error) {
+	if options.Decompress {
+		return countCompressed(r, options)
+	}
+	return count(r, options)
+}
+
+func count(r io.Reader, options Options) (Result, error) {
+	c := NewCounter(options)
+	buf := make([]byte, bufferSize)
+	_, err := io.CopyBuffer(c, r, buf)
+	c.finish()
+	return c.Result(), err
+}
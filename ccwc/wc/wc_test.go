@@ -0,0 +1,365 @@
+package wc
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// basicMetrics are the metrics whose results are plain numbers
+const basicMetrics = MetricBytes | MetricLines | MetricWords | MetricChars |
+	MetricMaxLineWidth | MetricLongestLine | MetricInvalid
+
+func TestCount(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		options Options
+		want    Result
+	}{
+		{
+			name: "empty",
+		},
+		{
+			name:  "one line",
+			input: "hello world\n",
+			want:  Result{Bytes: 12, CompressedBytes: 12, Lines: 1, Words: 2, Chars: 12, MaxLineWidth: 11, LongestLine: 1},
+		},
+		{
+			name:  "unterminated last line",
+			input: "a\nbbb",
+			want:  Result{Bytes: 5, CompressedBytes: 5, Lines: 1, Words: 2, Chars: 5, MaxLineWidth: 3, LongestLine: 2, PartialLine: true},
+		},
+		{
+			name:    "final partial line counted",
+			input:   "a\nbbb",
+			options: Options{CountFinalPartialLine: true},
+			want:    Result{Bytes: 5, CompressedBytes: 5, Lines: 2, Words: 2, Chars: 5, MaxLineWidth: 3, LongestLine: 2, PartialLine: true},
+		},
+		{
+			name:  "whitespace",
+			input: " \t a\u00a0b\r\n\v\fc ",
+			want:  Result{Bytes: 13, CompressedBytes: 13, Lines: 1, Words: 3, Chars: 12, MaxLineWidth: 12, LongestLine: 1, PartialLine: true},
+		},
+		{
+			name:  "multibyte",
+			input: "héllo 日本\n",
+			want:  Result{Bytes: 14, CompressedBytes: 14, Lines: 1, Words: 2, Chars: 9, MaxLineWidth: 10, LongestLine: 1},
+		},
+		{
+			name:  "invalid utf-8",
+			input: "a\xffb \xe2\x82",
+			want:  Result{Bytes: 6, CompressedBytes: 6, Words: 2, Chars: 3, Invalid: 3, MaxLineWidth: 3, LongestLine: 1, PartialLine: true},
+		},
+		{
+			name:  "invalid byte alone is a word",
+			input: "a \xff b\n",
+			want:  Result{Bytes: 6, CompressedBytes: 6, Lines: 1, Words: 3, Chars: 5, Invalid: 1, MaxLineWidth: 4, LongestLine: 1},
+		},
+		{
+			name:  "utf-16le lone surrogate",
+			input: "\xff\xfea\x00\x00\xdcb\x00",
+			want:  Result{Bytes: 8, CompressedBytes: 8, Words: 1, Chars: 2, Invalid: 1, MaxLineWidth: 2, LongestLine: 1, PartialLine: true},
+		},
+		{
+			name:  "utf-16le with byte order mark",
+			input: "\xff\xfeh\x00i\x00\n\x00",
+			want:  Result{Bytes: 8, CompressedBytes: 8, Lines: 1, Words: 1, Chars: 3, MaxLineWidth: 2, LongestLine: 1},
+		},
+		{
+			name:    "latin1",
+			input:   "caf\xe9\n",
+			options: Options{Encoding: EncodingLatin1},
+			want:    Result{Bytes: 5, CompressedBytes: 5, Lines: 1, Words: 1, Chars: 5, MaxLineWidth: 4, LongestLine: 1},
+		},
+		{
+			name:    "windows-1252 undefined byte",
+			input:   "a\x81b\n",
+			options: Options{Encoding: EncodingWindows1252},
+			want:    Result{Bytes: 4, CompressedBytes: 4, Lines: 1, Words: 1, Chars: 3, Invalid: 1, MaxLineWidth: 2, LongestLine: 1},
+		},
+		{
+			name:    "graphemes and unicode words",
+			input:   "e\u0301te\u0301 日本語\n",
+			options: Options{Graphemes: true, UnicodeWords: true},
+			want:    Result{Bytes: 18, CompressedBytes: 18, Lines: 1, Words: 4, Chars: 8, MaxLineWidth: 10, LongestLine: 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := tt.options
+			options.Metrics = basicMetrics
+			got, err := Count(strings.NewReader(tt.input), options)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Count(%q) = %+v, want %+v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+// countInputs are counted whole and in pieces, which must agree
+var countInputs = map[string]string{
+	"ascii":       strings.Repeat("The quick brown fox\tjumps over the lazy dog.\n", 50),
+	"multibyte":   strings.Repeat("héllo wörld 日本語 👨\u200d👩\u200d👧 🇯🇵\n", 20),
+	"invalid":     "a\xff\xfeb \xe2\x82 c\xc3",
+	"utf-16":      "\xff\xfe" + "h\x00i\x00 \x00=\xd8\x00\xde\n\x00",
+	"long line":   strings.Repeat("word ", 20000) + "\n" + strings.Repeat("x", 70000),
+	"code":        "// comment\nx := \"/* not a comment */\"\n\n/* block\n*/ y\n",
+	"no newlines": "one two three",
+}
+
+func TestCounterChunks(t *testing.T) {
+	options := Options{
+		Metrics:  MetricAll,
+		Patterns: []*regexp.Regexp{regexp.MustCompile(`o`)},
+	}
+	for name, input := range countInputs {
+		want, err := Count(strings.NewReader(input), options)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, size := range []int{1, 2, 3, 7, 64, 4096} {
+			c := NewCounter(options)
+			for p := []byte(input); len(p) > 0; {
+				n := min(size, len(p))
+				c.Write(p[:n])
+				p = p[n:]
+			}
+			c.finish()
+			if got := c.Result(); !reflect.DeepEqual(got, want) {
+				t.Errorf("%s in chunks of %d: %+v, want %+v", name, size, got, want)
+			}
+		}
+	}
+}
+
+func TestCounterResultDoesNotChangeState(t *testing.T) {
+	c := NewCounter(Options{Metrics: MetricAll})
+	c.Write([]byte("one tw"))
+	if got := c.Result(); got.Words != 2 || got.Lines != 0 {
+		t.Errorf("partial result: %+v", got)
+	}
+	c.Write([]byte("o\xe2"))
+	c.Result()
+	c.Write([]byte("\x82\xac three\n"))
+	got := c.Result()
+	if got.Words != 3 || got.Lines != 1 || got.Chars != 15 || got.Invalid != 0 {
+		t.Errorf("result after more writes: %+v", got)
+	}
+}
+
+// syncDo runs every task of CountAt in the calling goroutine
+func syncDo(task func()) {
+	task()
+}
+
+func TestCountAt(t *testing.T) {
+	// Every chunk boundary falls inside a word or a multibyte rune for
+	// some number of parts
+	var b strings.Builder
+	for b.Len() < 3*MinChunkSize {
+		b.WriteString("naïve words 日本語 across\tchunk   boundaries\n")
+	}
+	b.WriteString("unterminated")
+	input := b.String()
+
+	tests := []struct {
+		name    string
+		options Options
+	}{
+		{"splittable", Options{Metrics: MetricBytes | MetricLines | MetricWords | MetricChars | MetricInvalid}},
+		{"lines only", Options{Metrics: MetricLines}},
+		{"final partial line", Options{Metrics: MetricLines | MetricWords, CountFinalPartialLine: true}},
+		{"not splittable", Options{Metrics: MetricMaxLineWidth | MetricWords}},
+	}
+	for _, tt := range tests {
+		want, err := Count(strings.NewReader(input), tt.options)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, parts := range []int{1, 2, 3, 4, 7} {
+			got, err := CountAt(strings.NewReader(input), int64(len(input)), parts, tt.options, syncDo)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("%s in %d parts: %+v, want %+v", tt.name, parts, got, want)
+			}
+		}
+	}
+}
+
+func TestResultAdd(t *testing.T) {
+	a := Result{
+		Bytes: 10, CompressedBytes: 4, Lines: 2, Words: 3, Chars: 9, Invalid: 1,
+		MaxLineWidth: 8, LongestLine: 2, Code: 1, Blank: 1, Sentences: 2, Columns: 3,
+		Matches: []Match{{Count: 2, Lines: 1}},
+	}
+	b := Result{
+		Bytes: 5, CompressedBytes: 5, Lines: 1, Words: 1, Chars: 5,
+		MaxLineWidth: 12, LongestLine: 1, Comments: 2, Sentences: 1, Columns: 2,
+		Matches: []Match{{Count: 1, Lines: 1}, {Count: 4, Lines: 2}},
+	}
+	a.Add(b)
+
+	want := Result{
+		Bytes: 15, CompressedBytes: 9, Lines: 3, Words: 4, Chars: 14, Invalid: 1,
+		MaxLineWidth: 12, LongestLine: 1, Code: 1, Comments: 2, Blank: 1, Sentences: 3, Columns: 3,
+		Matches: []Match{{Count: 3, Lines: 2}, {Count: 4, Lines: 2}},
+	}
+	if !reflect.DeepEqual(a, want) {
+		t.Errorf("Add = %+v, want %+v", a, want)
+	}
+}
+
+func TestResultAddStats(t *testing.T) {
+	first, err := Count(strings.NewReader("a\nbbb\n"), Options{Metrics: MetricStats})
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := Count(strings.NewReader("cc dd\n"), Options{Metrics: MetricStats})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var total Result
+	total.Add(first)
+	total.Add(second)
+	s := total.Stats
+	if s.Lines != 3 || s.MinLength != 1 || s.MaxLength != 5 || s.Median() != 3 || s.Mean() != 3 {
+		t.Errorf("stats = %+v, median %v, mean %v", s, s.Median(), s.Mean())
+	}
+	if first.Stats.Lines != 2 {
+		t.Errorf("Add changed the stats it added from: %+v", first.Stats)
+	}
+}
+
+// benchmarkInputs are generated once for every benchmark
+var benchmarkInputs = map[string][]byte{
+	"ascii":     bytes.Repeat([]byte("The quick brown fox\tjumps over the lazy dog.\n"), 1<<16),
+	"multibyte": bytes.Repeat([]byte("Größenwahn und 日本語のテキスト, naïve café.\n"), 1<<16),
+}
+
+func BenchmarkCount(b *testing.B) {
+	modes := []struct {
+		name    string
+		options Options
+	}{
+		{"lines", Options{Metrics: MetricLines}},
+		{"default", Options{Metrics: MetricBytes | MetricLines | MetricWords}},
+		{"chars", Options{Metrics: MetricBytes | MetricLines | MetricWords | MetricChars}},
+		{"max line width", Options{Metrics: MetricLines | MetricMaxLineWidth}},
+		{"graphemes", Options{Metrics: MetricChars, Graphemes: true}},
+		{"all", Options{Metrics: MetricAll}},
+	}
+	for _, input := range []string{"ascii", "multibyte"} {
+		data := benchmarkInputs[input]
+		for _, mode := range modes {
+			b.Run(input+"/"+mode.name, func(b *testing.B) {
+				b.SetBytes(int64(len(data)))
+				for i := 0; i < b.N; i++ {
+					if _, err := Count(bytes.NewReader(data), mode.options); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
+
+func BenchmarkCountAt(b *testing.B) {
+	data := benchmarkInputs["ascii"]
+	options := Options{Metrics: MetricBytes | MetricLines | MetricWords}
+	for _, parts := range []int{1, 4} {
+		b.Run(fmt.Sprintf("parts=%d", parts), func(b *testing.B) {
+			b.SetBytes(int64(len(data)))
+			for i := 0; i < b.N; i++ {
+				if _, err := CountAt(bytes.NewReader(data), int64(len(data)), parts, options, syncDo); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// compressedText is "hello world\nbye\n" in each format. The standard library
+// cannot write bzip2, so it was compressed with bzip2(1).
+var compressedText = map[string][]byte{
+	"gzip": compress(func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) }),
+	"zlib": compress(func(w io.Writer) io.WriteCloser { return zlib.NewWriter(w) }),
+	"bzip2": []byte("BZh91AY&SY" +
+		"\xde\xac\xa8\x5a\x00\x00\x03\xd1\x80\x00\x10\x40\x00\x16\x44\x90\xa0\x20\x00\x31\x00\xd3\x4d" +
+		"\x04\x0d\x0d\x1a\x0a\x20\xad\x1d\x96\xc8\xf2\xf1\x77\x24\x53\x85\x09\x0d\xea\xca\x85\xa0"),
+}
+
+func compress(newWriter func(io.Writer) io.WriteCloser) []byte {
+	var buf bytes.Buffer
+	w := newWriter(&buf)
+	w.Write([]byte("hello world\nbye\n"))
+	w.Close()
+	return buf.Bytes()
+}
+
+func TestCountCompressed(t *testing.T) {
+	for format, data := range compressedText {
+		t.Run(format, func(t *testing.T) {
+			if !IsCompressed(data) {
+				t.Fatalf("%s input not recognized", format)
+			}
+			options := Options{Metrics: MetricBytes | MetricLines | MetricWords, Decompress: true}
+			got, err := Count(bytes.NewReader(data), options)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := Result{Bytes: 16, CompressedBytes: len(data), Lines: 2, Words: 3, Chars: 16}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("decompressed: %+v, want %+v", got, want)
+			}
+
+			path := filepath.Join(t.TempDir(), "input")
+			if err := os.WriteFile(path, data, 0o644); err != nil {
+				t.Fatal(err)
+			}
+			f, err := os.Open(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			got, err = CountFile(f, options)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("file decompressed: %+v, want %+v", got, want)
+			}
+
+			options.Decompress = false
+			got, err = Count(bytes.NewReader(data), options)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Bytes != len(data) || got.CompressedBytes != len(data) {
+				t.Errorf("not decompressed: %d bytes, %d compressed, want %d", got.Bytes, got.CompressedBytes, len(data))
+			}
+		})
+	}
+}
+
+func TestIsCompressed(t *testing.T) {
+	for _, text := range []string{"", "\x1f", "x^ is text", "BZh0", "BZhx", "plain"} {
+		if IsCompressed([]byte(text)) {
+			t.Errorf("%q is compressed", text)
+		}
+	}
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Duration is a time.Duration written as a string like "6s" or "500ms" in
+// the config file
+type Duration struct {
+	time.Duration
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return fmt.Errorf("duration must be a string like \"5s\", got %s", data)
+	}
+	duration, err := time.ParseDuration(text)
+	if err != nil {
+		return err
+	}
+	d.Duration = duration
+	return nil
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+func (d *Duration) UnmarshalYAML(unmarshal func(any) error) error {
+	var text string
+	if err := unmarshal(&text); err != nil {
+		return errors.New("duration must be a string like \"5s\"")
+	}
+	duration, err := time.ParseDuration(text)
+	if err != nil {
+		return err
+	}
+	d.Duration = duration
+	return nil
+}
+
+type Backend struct {
+	URL string `json:"url" yaml:"url"`
+	// MaxConcurrency is how many requests the backend handles at the same
+	// time
+	MaxConcurrency int `json:"max_concurrency" yaml:"max_concurrency"`
+	// Weight is how many requests the backend gets in every round, relative
+	// to the other backends. A weight of 0 drains the backend, which is still
+	// health checked but gets no requests.
+	Weight int `json:"weight" yaml:"weight"`
+}
+
+// UnmarshalYAML sets the fields missing from a backend to their defaults. A
+// field set to 0 is kept, since a weight of 0 drains a backend.
+func (b *Backend) UnmarshalYAML(unmarshal func(any) error) error {
+	type plain Backend
+	backend := plain{MaxConcurrency: defaultMaxConcurrency, Weight: defaultWeight}
+	if err := unmarshal(&backend); err != nil {
+		return err
+	}
+	*b = Backend(backend)
+	return nil
+}
+
+type HealthCheck struct {
+	// Path is requested with HEAD on every backend, which is healthy when
+	// the status is below 500
+	Path     string   `json:"path" yaml:"path"`
+	Interval Duration `json:"interval" yaml:"interval"`
+	Timeout  Duration `json:"timeout" yaml:"timeout"`
+}
+
+type Config struct {
+	Listen      string      `json:"listen" yaml:"listen"`
+	Backends    []Backend   `json:"backends" yaml:"backends"`
+	HealthCheck HealthCheck `json:"health_check" yaml:"health_check"`
+	// RequestTimeout bounds how long a request waits for a backend and its
+	// response. Zero means no timeout.
+	RequestTimeout Duration `json:"request_timeout" yaml:"request_timeout"`
+}
+
+const (
+	defaultMaxConcurrency = 5
+	defaultWeight         = 1
+)
+
+// defaultConfig is used without a config file, and its fields are the
+// defaults of the fields missing from one
+func defaultConfig() Config {
+	return Config{
+		Listen: ":80",
+		Backends: []Backend{
+			{URL: "http://localhost:8081", MaxConcurrency: defaultMaxConcurrency, Weight: defaultWeight},
+			{URL: "http://localhost:8082", MaxConcurrency: defaultMaxConcurrency, Weight: defaultWeight},
+		},
+		HealthCheck: HealthCheck{
+			Path:     "/",
+			Interval: Duration{6 * time.Second},
+			Timeout:  Duration{2 * time.Second},
+		},
+	}
+}
+
+// loadConfig reads a JSON config file, or a YAML one when its extension is
+// .yaml or .yml, and checks it. Unknown fields are an error, so a misspelled
+// field is not silently ignored.
+func loadConfig(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, err
+	}
+
+	config := defaultConfig()
+	config.Backends = nil
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.UnmarshalStrict(data, &config)
+	default:
+		err = decodeJSON(data, &config)
+	}
+	if err != nil {
+		return Config{}, fmt.Errorf("%s: %w", path, err)
+	}
+
+	if err := config.validate(); err != nil {
+		return Config{}, fmt.Errorf("%s: invalid config:\n%w", path, err)
+	}
+	return config, nil
+}
+
+// decodeJSON decodes a JSON config into config, whose fields are the
+// defaults of the fields missing from data
+func decodeJSON(data []byte, config *Config) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(config); err != nil {
+		return describeJSONError(data, err)
+	}
+
+	// Backends are decoded into zero values, so the fields missing from a
+	// backend are set here. A field set to 0 is kept, since a weight of 0
+	// drains a backend.
+	var fields struct {
+		Backends []map[string]json.RawMessage `json:"backends"`
+	}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	for i, backend := range fields.Backends {
+		if _, ok := backend["max_concurrency"]; !ok {
+			config.Backends[i].MaxConcurrency = defaultMaxConcurrency
+		}
+		if _, ok := backend["weight"]; !ok {
+			config.Backends[i].Weight = defaultWeight
+		}
+	}
+	return nil
+}
+
+// describeJSONError adds the line and column of a syntax or type error in
+// data
+func describeJSONError(data []byte, err error) error {
+	var offset int64
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntaxErr):
+		offset = syntaxErr.Offset
+	case errors.As(err, &typeErr):
+		offset = typeErr.Offset
+		if typeErr.Field != "" {
+			err = fmt.Errorf("%s: cannot use %s as %s", typeErr.Field, typeErr.Value, typeErr.Type)
+		}
+	default:
+		return err
+	}
+	line := 1 + bytes.Count(data[:offset], []byte("\n"))
+	column := offset - int64(bytes.LastIndexByte(data[:offset], '\n'))
+	return fmt.Errorf("line %d, column %d: %w", line, column, err)
+}
+
+// validate returns every problem of the config, one per line
+func (c Config) validate() error {
+	var errs []error
+	add := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if c.Listen == "" {
+		add("listen: must be an address like \":80\" or \"127.0.0.1:8080\"")
+	} else if !strings.Contains(c.Listen, ":") {
+		add("listen: %q has no port, use an address like \":80\"", c.Listen)
+	}
+
+	if len(c.Backends) == 0 {
+		add("backends: at least one backend is required")
+	}
+	drained := 0
+	seen := map[string]int{}
+	for i, b := range c.Backends {
+		u, err := url.Parse(b.URL)
+		switch {
+		case b.URL == "":
+			add("backends[%d].url: is required", i)
+		case err != nil:
+			add("backends[%d].url: %v", i, err)
+		case u.Scheme != "http" && u.Scheme != "https":
+			add("backends[%d].url: %q must start with http:// or https://", i, b.URL)
+		case u.Host == "":
+			add("backends[%d].url: %q has no host", i, b.URL)
+		}
+		if first, ok := seen[b.URL]; ok && b.URL != "" {
+			add("backends[%d].url: %q is already backends[%d]", i, b.URL, first)
+		} else {
+			seen[b.URL] = i
+		}
+		if b.MaxConcurrency <= 0 {
+			add("backends[%d].max_concurrency: must be positive, got %d", i, b.MaxConcurrency)
+		}
+		if b.Weight < 0 {
+			add("backends[%d].weight: must be 0 to drain the backend or positive, got %d", i, b.Weight)
+		}
+		if b.Weight == 0 {
+			drained++
+		}
+	}
+	if len(c.Backends) > 0 && drained == len(c.Backends) {
+		add("backends: every backend has a weight of 0, so no backend would get requests")
+	}
+
+	if !strings.HasPrefix(c.HealthCheck.Path, "/") {
+		add("health_check.path: %q must start with /", c.HealthCheck.Path)
+	}
+	if c.HealthCheck.Interval.Duration <= 0 {
+		add("health_check.interval: must be positive, got %v", c.HealthCheck.Interval)
+	}
+	if c.HealthCheck.Timeout.Duration <= 0 {
+		add("health_check.timeout: must be positive, got %v", c.HealthCheck.Timeout)
+	} else if c.HealthCheck.Timeout.Duration > c.HealthCheck.Interval.Duration {
+		add("health_check.timeout: %v is longer than the interval %v", c.HealthCheck.Timeout, c.HealthCheck.Interval)
+	}
+	if c.RequestTimeout.Duration < 0 {
+		add("request_timeout: must not be negative, got %v", c.RequestTimeout)
+	}
+	return errors.Join(errs...)
+}
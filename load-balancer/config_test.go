@@ -0,0 +1,218 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeConfig writes a config file named name and returns its path
+func writeConfig(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadConfig(t *testing.T) {
+	defaults := defaultConfig()
+	withBackends := func(backends ...Backend) Config {
+		config := defaults
+		config.Backends = backends
+		return config
+	}
+
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		want    Config
+	}{
+		{
+			name:    "yaml defaults",
+			file:    "lb.yaml",
+			content: "backends:\n  - url: http://a:1\n",
+			want:    withBackends(Backend{URL: "http://a:1", MaxConcurrency: defaultMaxConcurrency, Weight: defaultWeight}),
+		},
+		{
+			name:    "json defaults",
+			file:    "lb.json",
+			content: `{"backends": [{"url": "http://a:1"}]}`,
+			want:    withBackends(Backend{URL: "http://a:1", MaxConcurrency: defaultMaxConcurrency, Weight: defaultWeight}),
+		},
+		{
+			name:    "yaml weight 0 drains a backend",
+			file:    "lb.yml",
+			content: "backends:\n  - url: http://a:1\n    weight: 0\n  - url: http://b:1\n    max_concurrency: 2\n    weight: 3\n",
+			want: withBackends(
+				Backend{URL: "http://a:1", MaxConcurrency: defaultMaxConcurrency, Weight: 0},
+				Backend{URL: "http://b:1", MaxConcurrency: 2, Weight: 3},
+			),
+		},
+		{
+			name:    "json weight 0 drains a backend",
+			file:    "lb.json",
+			content: `{"backends": [{"url": "http://a:1", "weight": 0}, {"url": "http://b:1", "max_concurrency": 2, "weight": 3}]}`,
+			want: withBackends(
+				Backend{URL: "http://a:1", MaxConcurrency: defaultMaxConcurrency, Weight: 0},
+				Backend{URL: "http://b:1", MaxConcurrency: 2, Weight: 3},
+			),
+		},
+		{
+			name: "yaml health check keeps the defaults it does not set",
+			file: "lb.yaml",
+			content: "listen: \":8080\"\n# comment\nbackends:\n  - url: http://a:1\n" +
+				"health_check:\n  interval: 10s\nrequest_timeout: 30s\n",
+			want: func() Config {
+				config := withBackends(Backend{URL: "http://a:1", MaxConcurrency: defaultMaxConcurrency, Weight: defaultWeight})
+				config.Listen = ":8080"
+				config.HealthCheck.Interval = Duration{10 * time.Second}
+				config.RequestTimeout = Duration{30 * time.Second}
+				return config
+			}(),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := loadConfig(writeConfig(t, tt.file, tt.content))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("loadConfig = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadConfigExamples(t *testing.T) {
+	for _, path := range []string{"config.example.json", "config.example.yaml"} {
+		if _, err := loadConfig(path); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		want    []string // parts of the error
+	}{
+		{
+			name:    "yaml unknown field",
+			file:    "lb.yaml",
+			content: "backends:\n  - url: http://a:1\n    wieght: 2\n",
+			want:    []string{"line 3: field wieght not found"},
+		},
+		{
+			name:    "yaml duplicate key",
+			file:    "lb.yaml",
+			content: "listen: \":1\"\nlisten: \":2\"\n",
+			want:    []string{"line 2: field listen already set"},
+		},
+		{
+			name:    "yaml duration",
+			file:    "lb.yaml",
+			content: "health_check:\n  interval: 5\n",
+			want:    []string{`missing unit in duration "5"`},
+		},
+		{
+			name:    "yaml syntax",
+			file:    "lb.yaml",
+			content: "backends:\n\t- url: http://a:1\n",
+			want:    []string{"yaml: line 2"},
+		},
+		{
+			name:    "json unknown field",
+			file:    "lb.json",
+			content: `{"backends": [{"url": "http://a:1", "wieght": 2}]}`,
+			want:    []string{`json: unknown field "wieght"`},
+		},
+		{
+			name:    "json type",
+			file:    "lb.json",
+			content: "{\n  \"listen\": 80\n}",
+			want:    []string{"line 2, column 15: listen: cannot use number as string"},
+		},
+		{
+			name:    "every backend drained",
+			file:    "lb.yaml",
+			content: "backends:\n  - url: http://a:1\n    weight: 0\n",
+			want:    []string{"invalid config:\nbackends: every backend has a weight of 0, so no backend would get requests"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeConfig(t, tt.file, tt.content)
+			_, err := loadConfig(path)
+			if err == nil {
+				t.Fatal("no error")
+			}
+			if !strings.HasPrefix(err.Error(), path+": ") {
+				t.Errorf("error %q does not start with the path", err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("error %q does not contain %q", err, want)
+				}
+			}
+			if strings.HasSuffix(tt.file, ".yaml") && strings.Contains(err.Error(), "json") {
+				t.Errorf("YAML error %q mentions JSON", err)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	valid := defaultConfig()
+	tests := []struct {
+		name   string
+		change func(c *Config)
+		want   string
+	}{
+		{"valid", func(c *Config) {}, ""},
+		{"listen", func(c *Config) { c.Listen = "" }, `listen: must be an address like ":80" or "127.0.0.1:8080"`},
+		{"listen port", func(c *Config) { c.Listen = "localhost" }, `listen: "localhost" has no port, use an address like ":80"`},
+		{"no backends", func(c *Config) { c.Backends = nil }, "backends: at least one backend is required"},
+		{"url", func(c *Config) { c.Backends[0].URL = "" }, "backends[0].url: is required"},
+		{"url scheme", func(c *Config) { c.Backends[0].URL = "ftp://a" }, `backends[0].url: "ftp://a" must start with http:// or https://`},
+		{"url host", func(c *Config) { c.Backends[0].URL = "http://" }, `backends[0].url: "http://" has no host`},
+		{"duplicate url", func(c *Config) { c.Backends[1].URL = c.Backends[0].URL },
+			`backends[1].url: "http://localhost:8081" is already backends[0]`},
+		{"max concurrency", func(c *Config) { c.Backends[1].MaxConcurrency = 0 }, "backends[1].max_concurrency: must be positive, got 0"},
+		{"negative weight", func(c *Config) { c.Backends[0].Weight = -1 },
+			"backends[0].weight: must be 0 to drain the backend or positive, got -1"},
+		{"one backend drained", func(c *Config) { c.Backends[0].Weight = 0 }, ""},
+		{"every backend drained", func(c *Config) { c.Backends[0].Weight, c.Backends[1].Weight = 0, 0 },
+			"backends: every backend has a weight of 0, so no backend would get requests"},
+		{"health check path", func(c *Config) { c.HealthCheck.Path = "health" }, `health_check.path: "health" must start with /`},
+		{"health check interval", func(c *Config) { c.HealthCheck.Interval = Duration{} },
+			"health_check.interval: must be positive, got 0s\nhealth_check.timeout: 2s is longer than the interval 0s"},
+		{"health check timeout", func(c *Config) { c.HealthCheck.Timeout = Duration{} }, "health_check.timeout: must be positive, got 0s"},
+		{"request timeout", func(c *Config) { c.RequestTimeout = Duration{-time.Second} }, "request_timeout: must not be negative, got -1s"},
+		{"every problem", func(c *Config) { c.Listen = ""; c.Backends[0].MaxConcurrency = -2 },
+			"listen: must be an address like \":80\" or \"127.0.0.1:8080\"\nbackends[0].max_concurrency: must be positive, got -2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := valid
+			config.Backends = append([]Backend(nil), valid.Backends...)
+			tt.change(&config)
+			err := config.validate()
+			got := ""
+			if err != nil {
+				got = err.Error()
+			}
+			if got != tt.want {
+				t.Errorf("validate() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,3 +1,5 @@
 module daespuor91/load-balancer
 
 go 1.22.3
+
+require gopkg.in/yaml.v2 v2.4.0
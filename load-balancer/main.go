@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"io"
 	"log"
@@ -23,14 +24,15 @@ type Servers struct {
 	nextServerIndex int
 }
 
-func newServers() Servers {
-	data := map[string]Server{
-		"http://localhost:8081": {URL: "http://localhost:8081", Pool: make(chan bool, 5)},
-		"http://localhost:8082": {URL: "http://localhost:8082", Pool: make(chan bool, 5)},
-	}
-	urls := make([]string, len(data))
-	for serverURL, _ := range data {
-		urls = append(urls, serverURL)
+func newServers(backends []Backend) Servers {
+	data := make(map[string]Server, len(backends))
+	// A backend is picked in turn as many times as its weight
+	var urls []string
+	for _, backend := range backends {
+		data[backend.URL] = Server{URL: backend.URL, Pool: make(chan bool, backend.MaxConcurrency)}
+		for i := 0; i < backend.Weight; i++ {
+			urls = append(urls, backend.URL)
+		}
 	}
 
 	return Servers{
@@ -110,29 +112,29 @@ func doRequest(ctx context.Context, servers *Servers, w http.ResponseWriter, r *
 	return resp, nil
 }
 
-func verifyServers(ctx context.Context, servers *Servers, interval time.Duration) {
+func verifyServers(ctx context.Context, servers *Servers, check HealthCheck) {
 
-	ticker := time.NewTicker(interval)
+	ticker := time.NewTicker(check.Interval.Duration)
 	defer ticker.Stop()
 
 	for {
 		select {
 		case <-ticker.C:
-			checkServersStatus(ctx, servers)
+			checkServersStatus(ctx, servers, check)
 		case <-ctx.Done():
 			return
 		}
 	}
 }
 
-func checkServersStatus(ctx context.Context, servers *Servers) {
+func checkServersStatus(ctx context.Context, servers *Servers, check HealthCheck) {
 	servers.Lock()
 	defer servers.Unlock()
 
 	for serverURL, server := range servers.data {
-		checkCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+		checkCtx, cancel := context.WithTimeout(ctx, check.Timeout.Duration)
 
-		req, err := http.NewRequestWithContext(checkCtx, "HEAD", serverURL, nil)
+		req, err := http.NewRequestWithContext(checkCtx, "HEAD", serverURL+check.Path, nil)
 		if err != nil {
 			server := servers.data[serverURL]
 			server.isHealthy = false
@@ -166,21 +168,38 @@ func checkServersStatus(ctx context.Context, servers *Servers) {
 }
 
 func main() {
+	configPath := flag.String("config", "", "Path of a JSON or YAML config file with the listen address, backends and timeouts")
+	flag.Parse()
+
+	config := defaultConfig()
+	if *configPath != "" {
+		var err error
+		if config, err = loadConfig(*configPath); err != nil {
+			log.Fatal(err)
+		}
+	}
+
 	log.Println("Starting up load balancer...")
 	defer log.Println("Shutting down load balancer")
 
-	servers := newServers()
+	servers := newServers(config.Backends)
 	log.Printf("Initializing %d backend servers\n", len(servers.data))
 
 	mux := http.NewServeMux()
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	go verifyServers(ctx, &servers, 6*time.Second)
+	go verifyServers(ctx, &servers, config.HealthCheck)
 
 	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		reqCtx := r.Context()
+		if config.RequestTimeout.Duration > 0 {
+			var cancel context.CancelFunc
+			reqCtx, cancel = context.WithTimeout(reqCtx, config.RequestTimeout.Duration)
+			defer cancel()
+		}
 
-		resp, err := doRequest(r.Context(), &servers, w, r)
+		resp, err := doRequest(reqCtx, &servers, w, r)
 		if err != nil {
 			log.Printf("error forwarding the request: %v\n", err)
 			http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -198,5 +217,8 @@ func main() {
 		io.Copy(w, resp.Body)
 	})
 
-	http.ListenAndServe(":80", mux)
+	log.Printf("Listening on %s\n", config.Listen)
+	if err := http.ListenAndServe(config.Listen, mux); err != nil {
+		log.Printf("error serving: %v\n", err)
+	}
 }